@@ -3,91 +3,425 @@ package main
 //Archivo con la función main del cliente
 
 import (
+	"Client/client"
+	"context"
+	"flag"
 	"fmt"
+	"io"
+	"net"
 	"os"
 	"strconv"
 	"strings"
+	"time"
 )
 
 //Constantes
-const NUMBER_OF_CHANNELS = 8   //Cantidad de canales disponibles para que un cliente se suscriba
-const BUFFER_SIZE = 1024       //Tamaño de un buffer temporal utilizado para leer archivos iterativamente
-const SERVER_PORT = "7101"     //Puerto en el que opera el servidor
-const FILENAME_MAX_LENGTH = 40 //Tamaño máximo del nombre de un archivo que se recibe
+const NUMBER_OF_CHANNELS = 8                  //Cantidad de canales disponibles para que un cliente se suscriba
+const DEFAULT_GRACE_PERIOD = 30 * time.Second //Tiempo que se espera a que terminen las transferencias en curso al interrumpir el modo de recepción
+const STANDARD_INPUT_ARGUMENT = "-"           //Archivo a enviar que corresponde a la entrada estándar
 
 func main() {
 	//Verificar argumentos
-	if len(os.Args) < 5 || len(os.Args) > 6 {
-		fmt.Print("File sharing client: Send and receive files using channels through a TCP server\n\n")
-		fmt.Println("Usage:")
-		fmt.Println("Receive mode:\t client receive -channel CHANNEL -path DOWNLOAD_PATH")
-		fmt.Println("Send mode:\t client send FILE -channel CHANNEL")
-		fmt.Println("\nExamples:")
-		fmt.Println("client receive -channel 3 -path D:\\Downloads\\ //Receive files sent by other clients to channel 3, saving them to selected download path")
-		fmt.Println("client send test.txt -channel 4 //Send file test.txt to clients currently subscribed to channel 4")
+	if len(os.Args) < 2 {
+		printUsage()
 		os.Exit(0)
 	}
-	//Verificar flags
-	validateFlags()
 
 	var mode string = os.Args[1]
-	var channel int8
-	var filepath string
+	var channel client.Channel
 	//Determinar el modo seleccionado por el cliente
 	switch mode {
 	case "receive":
-		//Leer canal y, opcionalmente, path de descarga de archivos
-		channel = parseChannel(os.Args[3])
-		var downloadPath string = parseDownloadPath(os.Args[5])
+		//Leer canal, path de descarga de archivos y opciones del modo
+		var flags *flag.FlagSet = newFlagSet(mode)
+		var channelFlag *string = flags.String("channel", "", "channel, or list and ranges of channels (e.g. 1,3-5,builds), to subscribe to")
+		var channelDirsFlag *bool = flags.Bool("channel-dirs", false, "save the files of each channel in a subdirectory named after it")
+		var noMetadataFlag *bool = flags.Bool("no-metadata", false, "don't apply the modification time and permissions sent with each file")
+		var pathFlag *string = flags.String("path", "", "directory where received files are saved")
+		var conflictFlag *string = flags.String("on-conflict", client.CONFLICT_OVERWRITE, "policy for received files whose name already exists (overwrite, skip, rename, timestamp)")
+		var passphraseFlag *string = flags.String("passphrase-file", "", "file with the channel passphrase used to decrypt end-to-end encrypted files")
+		var listenFlag *string = flags.String("listen", client.DEFAULT_LISTEN_ADDRESS, "address (host:port) where the server's connections are accepted")
+		var advertiseFlag *string = flags.String("advertise", "", "address (host[:port]) the server uses to reach the listener")
+		var pushFlag *bool = flags.Bool("push", false, "receive files through the subscription connection instead of a listener")
+		var stdoutFlag *bool = flags.Bool("stdout", false, "write the first received file to standard output and exit")
+		var framesFlag *bool = flags.Bool("frames", false, "with -stdout, write every received file to standard output as a frame")
+		var graceFlag *time.Duration = flags.Duration("grace", DEFAULT_GRACE_PERIOD, "time to wait for transfers in progress to finish when interrupted (0 = abort them)")
+		var serverFlag *string = flags.String("server", "", "address (host:port) of the server")
+		var configFlag *string = flags.String("config", "", "configuration file")
+		var tlsOptions tlsFlags = addTLSFlags(flags)
+		var timeoutOptions timeoutFlags = addTimeoutFlags(flags)
+		var arguments []string = parseArguments(flags, os.Args[2:])
+		if len(arguments) != 0 {
+			fmt.Println("ERROR: Unexpected argument \"" + arguments[0] + "\"")
+			os.Exit(1)
+		}
+		requireFlag(flags, "channel")
+		if *stdoutFlag {
+			for _, name := range []string{"path", "on-conflict", "channel-dirs", "no-metadata"} {
+				if isFlagSet(flags, name) {
+					fmt.Println("ERROR: Flag \"-" + name + "\" can't be used with \"-stdout\"")
+					os.Exit(1)
+				}
+			}
+		} else if *framesFlag {
+			fmt.Println("ERROR: Flag \"-frames\" requires \"-stdout\"")
+			os.Exit(1)
+		} else {
+			requireFlag(flags, "path")
+		}
+		var channels []client.Channel = parseChannels(*channelFlag)
+		if *graceFlag < 0 {
+			fmt.Println("ERROR: Grace period can't be negative")
+			os.Exit(1)
+		}
+		var handler client.Handler
+		if *stdoutFlag {
+			//La salida estándar queda solo para los archivos recibidos: el resto de los mensajes se muestra en la salida
+			//de errores
+			handler = &client.WriterHandler{Writer: os.Stdout, Framed: *framesFlag}
+			os.Stdout = os.Stderr
+		} else {
+			handler = &client.DiskHandler{
+				Path:               parseDownloadPath(*pathFlag),
+				ConflictPolicy:     parseConflictPolicy(*conflictFlag),
+				ChannelDirectories: *channelDirsFlag,
+				IgnoreMetadata:     *noMetadataFlag,
+				Log:                os.Stdout,
+			}
+		}
+		//En el modo push no se utiliza un listener
+		if *pushFlag && (isFlagSet(flags, "listen") || isFlagSet(flags, "advertise")) {
+			fmt.Println("ERROR: Flags \"-listen\" and \"-advertise\" can't be used with \"-push\"")
+			os.Exit(1)
+		}
+		if *pushFlag && len(channels) > 1 {
+			fmt.Println("ERROR: Flag \"-push\" supports a single channel")
+			os.Exit(1)
+		}
+		var config map[string]string = loadConfig(*configFlag)
+		resolveServerAddress(*serverFlag, config)
+		loadTLSConfig(tlsOptions, !*pushFlag)
+		var fileClient *client.Client = newClient()
+		fileClient.ChannelAliases = loadChannelAliases(config)
+		applyTimeoutFlags(fileClient, timeoutOptions)
+		fileClient.Passphrase = parsePassphrase(*passphraseFlag)
+		fileClient.ListenAddress = parseListenAddress(*listenFlag)
+		fileClient.Advertise = parseAdvertiseAddress(*advertiseFlag)
+		fileClient.Push = *pushFlag
+		fileClient.GracePeriod = *graceFlag
 
-		subscribeToChannel(channel, downloadPath)
+		//Sin tramas, se recibe un solo archivo
+		if *stdoutFlag && !*framesFlag {
+			receiveSingleFile(fileClient, channels, handler)
+		} else {
+			subscribeToChannels(context.Background(), fileClient, channels, handler)
+		}
 	case "send":
 		//Leer canal y path del archivo a enviar
-		channel = parseChannel(os.Args[4])
-		filepath = os.Args[2]
+		var flags *flag.FlagSet = newFlagSet(mode)
+		var channelFlag *string = flags.String("channel", "", "channel (number or name) to send the file to")
+		var retriesFlag *int = flags.Int("retries", client.DEFAULT_RETRIES, "times to resume the transfer if the connection drops")
+		var parallelFlag *int = flags.Int("parallel", 1, "number of files sent at the same time")
+		var recursiveFlag *bool = flags.Bool("r", false, "send directories with all their contents")
+		var nameFlag *string = flags.String("name", "", "name of the file read from standard input (\"-\")")
+		var passphraseFlag *string = flags.String("passphrase-file", "", "file with the channel passphrase used to encrypt the file end-to-end")
+		var serverFlag *string = flags.String("server", "", "address (host:port) of the server")
+		var configFlag *string = flags.String("config", "", "configuration file")
+		var tlsOptions tlsFlags = addTLSFlags(flags)
+		var timeoutOptions timeoutFlags = addTimeoutFlags(flags)
+		var arguments []string = parseArguments(flags, os.Args[2:])
+		if len(arguments) == 0 {
+			fmt.Println("ERROR: Expected at least one file to send")
+			os.Exit(1)
+		}
+		requireFlag(flags, "channel")
+		channel = parseChannel(*channelFlag)
+		if *retriesFlag < 0 {
+			fmt.Println("ERROR: Number of retries can't be negative")
+			os.Exit(1)
+		}
+		if *parallelFlag < 1 {
+			fmt.Println("ERROR: Number of parallel transfers must be at least 1")
+			os.Exit(1)
+		}
+		if *recursiveFlag && isFlagSet(flags, "parallel") {
+			fmt.Println("ERROR: Flag \"-parallel\" can't be used with \"-r\"")
+			os.Exit(1)
+		}
+		//El archivo "-" corresponde a la entrada estándar, que se envía con el nombre indicado en -name
+		var standardInput bool = containsString(arguments, STANDARD_INPUT_ARGUMENT)
+		if standardInput && (len(arguments) != 1 || *recursiveFlag) {
+			fmt.Println("ERROR: Standard input (\"" + STANDARD_INPUT_ARGUMENT + "\") must be the only file to send")
+			os.Exit(1)
+		}
+		if standardInput {
+			requireFlag(flags, "name")
+			validateStandardInputName(*nameFlag)
+		} else if isFlagSet(flags, "name") {
+			fmt.Println("ERROR: Flag \"-name\" can only be used when sending standard input (\"" + STANDARD_INPUT_ARGUMENT + "\")")
+			os.Exit(1)
+		}
+		//Todos los archivos (o directorios) se verifican antes de enviar alguno
+		var paths []string
+		if standardInput {
+			paths = arguments
+		} else if *recursiveFlag {
+			paths = expandDirectoryArguments(arguments)
+		} else {
+			paths = expandFileArguments(arguments)
+		}
+		var config map[string]string = loadConfig(*configFlag)
+		resolveServerAddress(*serverFlag, config)
+		loadTLSConfig(tlsOptions, false)
+		var fileClient *client.Client = newClient()
+		fileClient.ChannelAliases = loadChannelAliases(config)
+		applyTimeoutFlags(fileClient, timeoutOptions)
+		fileClient.Retries = *retriesFlag
+		fileClient.Passphrase = parsePassphrase(*passphraseFlag)
 
-		sendFileThroughChannel(channel, filepath)
+		if standardInput {
+			sendStandardInput(fileClient, channel, *nameFlag)
+		} else if *recursiveFlag {
+			sendDirectoriesThroughChannel(fileClient, channel, paths)
+		} else {
+			sendFilesThroughChannel(fileClient, channel, paths, *parallelFlag)
+		}
 	default:
 		fmt.Println("ERROR: Invalid command \"" + mode + "\"")
 		os.Exit(1)
 	}
 }
 
-func validateFlags() {
-	switch len(os.Args) {
-	case 5:
-		//Only validate channel flag
-		if os.Args[3] != "-channel" {
-			fmt.Println("ERROR: Incorrect flag (expected \"-channel\", got \"" + os.Args[3] + "\")")
+//Función que muestra la forma de uso del cliente
+func printUsage() {
+	fmt.Print("File sharing client: Send and receive files using channels through a TCP server\n\n")
+	fmt.Println("Usage:")
+	fmt.Println("Receive mode:\t client receive -channel CHANNELS -path DOWNLOAD_PATH [-channel-dirs] [-on-conflict POLICY] [-no-metadata] [-passphrase-file FILE] [-listen ADDRESS] [-advertise ADDRESS] [-push] [-grace TIME] [SERVER OPTIONS] [TLS OPTIONS] [TIMEOUT OPTIONS]")
+	fmt.Println("\t\t client receive -channel CHANNELS -stdout [-frames] [-passphrase-file FILE] [-listen ADDRESS] [-advertise ADDRESS] [-push] [-grace TIME] [SERVER OPTIONS] [TLS OPTIONS] [TIMEOUT OPTIONS]")
+	fmt.Println("Send mode:\t client send FILE... -channel CHANNEL [-parallel N] [-retries N] [-passphrase-file FILE] [SERVER OPTIONS] [TLS OPTIONS] [TIMEOUT OPTIONS]")
+	fmt.Println("\t\t client send - -name NAME -channel CHANNEL [-retries N] [-passphrase-file FILE] [SERVER OPTIONS] [TLS OPTIONS] [TIMEOUT OPTIONS]")
+	fmt.Println("\t\t client send -r DIRECTORY... -channel CHANNEL [-retries N] [-passphrase-file FILE] [SERVER OPTIONS] [TLS OPTIONS] [TIMEOUT OPTIONS]")
+	fmt.Println("\nReceive options:")
+	fmt.Println("-channel CHANNELS\t Channel, or comma-separated list of channels and ranges of numeric channels (e.g. 1,3-5,builds),")
+	fmt.Println("\t\t\t received through one listener")
+	fmt.Println("-channel-dirs\t\t Save the files of each channel in a subdirectory of the download path named after the channel")
+	fmt.Println("-on-conflict POLICY\t What to do when a received file already exists: overwrite (default), skip, rename, timestamp")
+	fmt.Println("-no-metadata\t\t Don't apply the modification time and permissions sent with each file (received files get the")
+	fmt.Println("\t\t\t current time and default permissions)")
+	fmt.Println("-passphrase-file FILE\t File with the channel passphrase, used to decrypt end-to-end encrypted files")
+	fmt.Println("-listen HOST:PORT\t Address where the server's connections are accepted (default " + client.DEFAULT_LISTEN_ADDRESS + ", port 0 = random)")
+	fmt.Println("-advertise HOST[:PORT]\t Address the server connects to, if different from the listen address (e.g. behind port forwarding;")
+	fmt.Println("\t\t\t required when listening on all interfaces). Without a port, the listener's port is used")
+	fmt.Println("-push\t\t\t Receive files through the subscription connection, without a listener (requires server support;")
+	fmt.Println("\t\t\t useful behind NAT or firewalls)")
+	fmt.Println("-grace TIME\t\t Time to wait for transfers in progress to finish when interrupted (default " + DEFAULT_GRACE_PERIOD.String() + ";")
+	fmt.Println("\t\t\t 0 = abort them). A second interruption exits immediately")
+	fmt.Println("-stdout\t\t\t Write the first received file to standard output and exit with the result of its transfer")
+	fmt.Println("\t\t\t (messages are shown on standard error)")
+	fmt.Println("-frames\t\t\t With -stdout, keep receiving and write every file as a frame: name length (2 bytes), name, and")
+	fmt.Println("\t\t\t content in chunks prefixed by their length (4 bytes, little endian), ending with a 0 chunk")
+	fmt.Println("\t\t\t (or 0xFFFFFFFF if the transfer failed)")
+	fmt.Println("\nSend options:")
+	fmt.Println("FILE...\t\t\t Files to send; patterns such as 'logs/*.gz' are expanded. All files are checked before sending any")
+	fmt.Println("-parallel N\t\t Number of files sent at the same time (default 1). With several files, a summary is shown at the end")
+	fmt.Println("- -name NAME\t\t Send standard input as a file named NAME. With server support it is sent in chunks as it is read;")
+	fmt.Println("\t\t\t otherwise it is buffered to a temporary file first")
+	fmt.Println("-r\t\t\t Send directories with all their files and subdirectories, followed by a manifest that lets the receiver")
+	fmt.Println("\t\t\t check that the directory is complete (requires server support for long file names)")
+	fmt.Println("-retries N\t\t Times to resume the transfer if the connection drops (default 3, requires server support)")
+	fmt.Println("-passphrase-file FILE\t File with the channel passphrase, used to encrypt the file end-to-end")
+	fmt.Println("\nThe passphrase can also be set with the " + PASSPHRASE_ENVIRONMENT_VARIABLE + " environment variable.")
+	fmt.Println("\nChannels are numbers (1-" + strconv.Itoa(NUMBER_OF_CHANNELS) + ") or names such as build-artifacts (letters, digits, '-', '_' and '.'), which require")
+	fmt.Println("server support. For servers without named channels, a \"" + CHANNEL_ALIAS_PREFIX + "NAME = NUMBER\" line in the configuration file maps a name to a number.")
+	fmt.Println("\nServer options:")
+	fmt.Println("-server HOST[:PORT]\t Address of the server (default " + client.DEFAULT_SERVER_ADDRESS + "; IPv6 literals go in brackets: [::1]:" + client.SERVER_PORT + ")")
+	fmt.Println("-config FILE\t\t Configuration file with \"key = value\" lines (default: " + CONFIG_DIRECTORY + "/" + CONFIG_FILENAME + " in the user configuration directory)")
+	fmt.Println("\nThe server can also be set with the " + SERVER_ENVIRONMENT_VARIABLE + " environment variable or the \"server\" key of the")
+	fmt.Println("configuration file. The flag takes precedence over the environment variable, and both over the configuration file.")
+	fmt.Println("\nTLS options:")
+	fmt.Println("-tls\t\t\t Use TLS for all connections (in receive mode, also for the connections made by the server)")
+	fmt.Println("-ca FILE\t\t PEM bundle of CA certificates used to verify the server (default: system CAs; required in receive mode, except with -push)")
+	fmt.Println("-cert FILE -key FILE\t PEM client certificate and private key (required in receive mode, except with -push)")
+	fmt.Println("-server-name NAME\t Name expected in the server's certificate (default: server host)")
+	fmt.Println("\nTimeout options (durations such as 30s or 5m; 0 = no limit):")
+	fmt.Println("-connect-timeout TIME\t Maximum time to connect to the server (default " + client.DEFAULT_CONNECT_TIMEOUT.String() + ")")
+	fmt.Println("-read-timeout TIME\t Maximum time without receiving data from a connection (default " + client.DEFAULT_IDLE_TIMEOUT.String() + ")")
+	fmt.Println("-write-timeout TIME\t Maximum time without being able to send data through a connection (default " + client.DEFAULT_IDLE_TIMEOUT.String() + ")")
+	fmt.Println("-transfer-timeout TIME\t Maximum duration of a file transfer (default: no limit)")
+	fmt.Println("\nExit codes: 0 success, 1 usage, 2 communication, 3 protocol, 4 integrity, 5 filesystem, 6 timeout")
+	fmt.Println("\nExamples:")
+	fmt.Println("client receive -channel 3 -path D:\\Downloads\\ //Receive files sent by other clients to channel 3, saving them to selected download path")
+	fmt.Println("client receive -channel 3 -path D:\\Downloads\\ -on-conflict rename //Same as above, but keep existing files by renaming new ones")
+	fmt.Println("client receive -channel 1,3-5 -path ./downloads -channel-dirs //Receive files sent to channels 1, 3, 4 and 5, each in its own subdirectory")
+	fmt.Println("client receive -channel 3 -path ./downloads -listen 0.0.0.0:7200 -advertise 203.0.113.7:17200 //Receive behind port forwarding")
+	fmt.Println("client send test.txt -channel 4 //Send file test.txt to clients currently subscribed to channel 4")
+	fmt.Println("client send a.txt b.bin 'logs/*.gz' -channel 4 -parallel 3 //Send several files, up to 3 at a time")
+	fmt.Println("pg_dump mydb | client send - -name db.sql -channel 2 //Send the output of a command as file db.sql")
+	fmt.Println("client receive -channel 2 -stdout | tar x //Receive one file and extract it")
+	fmt.Println("client send -r build/output -channel 4 //Send directory build/output, received as output/ in the download path")
+	fmt.Println("client send test.txt -channel 4 -server files.example.com:7101 //Send file test.txt through a remote server")
+	fmt.Println("client send test.txt -channel 4 -tls -ca ca.pem //Same as above, verifying the server with a private CA")
+}
+
+//Función que crea el cliente de la biblioteca con la configuración común a ambos modos (servidor y TLS)
+func newClient() *client.Client {
+	return &client.Client{
+		Server:            serverAddress,
+		TLSConfig:         clientTLSConfig,
+		ListenerTLSConfig: listenerTLSConfig,
+		Retries:           client.DEFAULT_RETRIES,
+		Log:               os.Stdout,
+	}
+}
+
+//Opciones de tiempos máximos de espera indicadas por línea de comandos
+type timeoutFlags struct {
+	connect  *time.Duration //Tiempo máximo para conectarse al servidor
+	read     *time.Duration //Tiempo máximo sin recibir datos
+	write    *time.Duration //Tiempo máximo sin poder enviar datos
+	transfer *time.Duration //Tiempo máximo de una transferencia
+}
+
+//Función que añade las opciones de tiempos máximos de espera a los flags de un modo del cliente
+func addTimeoutFlags(flags *flag.FlagSet) timeoutFlags {
+	return timeoutFlags{
+		connect:  flags.Duration("connect-timeout", client.DEFAULT_CONNECT_TIMEOUT, "maximum time to connect to the server (0 = no limit)"),
+		read:     flags.Duration("read-timeout", client.DEFAULT_IDLE_TIMEOUT, "maximum time without receiving data (0 = no limit)"),
+		write:    flags.Duration("write-timeout", client.DEFAULT_IDLE_TIMEOUT, "maximum time without being able to send data (0 = no limit)"),
+		transfer: flags.Duration("transfer-timeout", 0, "maximum duration of a file transfer (0 = no limit)"),
+	}
+}
+
+//Función que aplica al cliente los tiempos máximos de espera indicados (en la biblioteca, un tiempo negativo indica
+//que no hay límite y 0 corresponde al valor por defecto)
+func applyTimeoutFlags(fileClient *client.Client, options timeoutFlags) {
+	var timeouts []*time.Duration = []*time.Duration{options.connect, options.read, options.write, options.transfer}
+	for _, timeout := range timeouts {
+		if *timeout < 0 {
+			fmt.Println("ERROR: Timeouts can't be negative")
 			os.Exit(1)
 		}
-	case 6:
-		//Validate both channel and path flags
-		if os.Args[2] != "-channel" {
-			fmt.Println("ERROR: Incorrect flag (expected \"-channel\", got \"" + os.Args[2] + "\")")
-			os.Exit(1)
+		if *timeout == 0 {
+			*timeout = -1
 		}
-		if os.Args[4] != "-path" {
-			fmt.Println("ERROR: Incorrect flag (expected \"-path\", got \"" + os.Args[4] + "\")")
+	}
+	fileClient.ConnectTimeout = *options.connect
+	fileClient.ReadTimeout = *options.read
+	fileClient.WriteTimeout = *options.write
+	fileClient.TransferTimeout = *options.transfer
+}
+
+//Función que crea el conjunto de flags de un modo del cliente
+func newFlagSet(mode string) *flag.FlagSet {
+	var flags *flag.FlagSet = flag.NewFlagSet(mode, flag.ContinueOnError)
+	//Los errores se muestran con el formato del cliente en vez del formato por defecto del paquete flag
+	flags.SetOutput(io.Discard)
+	return flags
+}
+
+//Función que parsea los flags de un modo y retorna los argumentos posicionales (que pueden estar antes o después de
+//los flags, por ejemplo: client send FILE -channel CHANNEL)
+func parseArguments(flags *flag.FlagSet, arguments []string) []string {
+	var positional []string
+	for {
+		parseError := flags.Parse(arguments)
+		//Error check
+		if parseError == flag.ErrHelp {
+			printUsage()
+			os.Exit(0)
+		} else if parseError != nil {
+			fmt.Println("ERROR: " + parseError.Error())
 			os.Exit(1)
 		}
+		if flags.NArg() == 0 {
+			return positional
+		}
+		positional = append(positional, flags.Arg(0))
+		arguments = flags.Args()[1:]
 	}
 }
 
-//Función para parsear el canal a partir de un string y verificar su validez
-func parseChannel(channelStr string) int8 {
-	//Conversión del canal a un entero
-	channel, parseError := strconv.Atoi(channelStr)
-	if parseError != nil {
-		fmt.Println("ERROR: Channel is not a valid number: " + parseError.Error())
+//Función que indica si un flag fue indicado en la línea de comandos
+func isFlagSet(flags *flag.FlagSet, name string) bool {
+	var found bool = false
+	flags.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			found = true
+		}
+	})
+	return found
+}
+
+//Función que verifica que un flag obligatorio haya sido indicado
+func requireFlag(flags *flag.FlagSet, name string) {
+	if !isFlagSet(flags, name) {
+		fmt.Println("ERROR: Missing flag \"-" + name + "\"")
 		os.Exit(1)
 	}
-	//Se verifica un canal válido (el valor máximo se verifica al conectarse con el servidor)
-	if channel < 1 || channel > NUMBER_OF_CHANNELS {
+}
+
+//Función para parsear un canal (número o nombre) a partir de un string y verificar su validez
+func parseChannel(channelStr string) client.Channel {
+	//Se verifica que un canal numérico sea válido (el valor máximo se verifica al conectarse con el servidor)
+	if number, numberError := strconv.Atoi(channelStr); numberError == nil && (number < 1 || number > NUMBER_OF_CHANNELS) {
 		fmt.Println("ERROR: Channel is outside valid range (1-" + strconv.Itoa(NUMBER_OF_CHANNELS) + ")")
 		os.Exit(1)
 	}
-	return int8(channel)
+	channel, parseError := client.ParseChannel(channelStr)
+	if parseError != nil {
+		fmt.Println("ERROR: Invalid channel: " + parseError.Error())
+		os.Exit(1)
+	}
+	return channel
+}
+
+//Función para parsear una lista de canales y rangos de canales numéricos separados por comas (por ejemplo,
+//1,3-5,builds)
+func parseChannels(channelsStr string) []client.Channel {
+	var channels []client.Channel
+	for _, item := range strings.Split(channelsStr, ",") {
+		item = strings.TrimSpace(item)
+		var itemChannels []client.Channel
+		//Un rango tiene números en ambos extremos (los nombres de canales pueden contener guiones, incluso tras un número,
+		//como 2024-builds)
+		var bounds []string = strings.SplitN(item, "-", 2)
+		var isRange bool = false
+		if len(bounds) == 2 {
+			_, firstError := strconv.Atoi(bounds[0])
+			_, lastError := strconv.Atoi(bounds[1])
+			isRange = firstError == nil && lastError == nil
+		}
+		if isRange {
+			var first int8 = parseChannel(bounds[0]).Number
+			var last int8 = parseChannel(bounds[1]).Number
+			if last < first {
+				fmt.Println("ERROR: Invalid channel range \"" + item + "\"")
+				os.Exit(1)
+			}
+			for number := first; number <= last; number++ {
+				itemChannels = append(itemChannels, client.NumberedChannel(number))
+			}
+		} else {
+			itemChannels = append(itemChannels, parseChannel(item))
+		}
+		//Los canales repetidos se ignoran
+		for _, channel := range itemChannels {
+			if !containsChannel(channels, channel) {
+				channels = append(channels, channel)
+			}
+		}
+	}
+	return channels
+}
+
+//Función que indica si un canal está en una lista de canales
+func containsChannel(channels []client.Channel, channel client.Channel) bool {
+	for _, listed := range channels {
+		if listed == channel {
+			return true
+		}
+	}
+	return false
 }
 
 func parseDownloadPath(path string) string {
@@ -103,3 +437,73 @@ func parseDownloadPath(path string) string {
 	}
 	return path
 }
+
+//Función para verificar la política de conflictos de nombres elegida
+func parseConflictPolicy(policy string) string {
+	switch policy {
+	case client.CONFLICT_OVERWRITE, client.CONFLICT_SKIP, client.CONFLICT_RENAME, client.CONFLICT_TIMESTAMP:
+		return policy
+	default:
+		fmt.Println("ERROR: Invalid conflict policy \"" + policy + "\" (expected overwrite, skip, rename or timestamp)")
+		os.Exit(1)
+	}
+	return ""
+}
+
+//Función para obtener la frase secreta del canal (de un archivo o de la variable de entorno correspondiente)
+func parsePassphrase(passphraseFile string) []byte {
+	passphrase, passphraseError := loadPassphrase(passphraseFile)
+	if passphraseError != nil {
+		fmt.Println("ERROR: Error while reading passphrase: " + passphraseError.Error())
+		os.Exit(1)
+	}
+	return passphrase
+}
+
+//Función para verificar la dirección en la que escucha el listener de recepción
+func parseListenAddress(address string) string {
+	_, port, splitError := net.SplitHostPort(address)
+	if splitError != nil {
+		fmt.Println("ERROR: Invalid listen address: " + splitError.Error())
+		os.Exit(1)
+	}
+	portNumber, portError := strconv.Atoi(port)
+	if portError != nil || portNumber < 0 || portNumber > 65535 {
+		fmt.Println("ERROR: Invalid port in listen address \"" + address + "\"")
+		os.Exit(1)
+	}
+	return address
+}
+
+//Función para verificar la dirección que se anuncia al servidor (el puerto es opcional)
+func parseAdvertiseAddress(address string) string {
+	if address == "" {
+		return address
+	}
+	host, port, splitError := net.SplitHostPort(address)
+	if splitError != nil {
+		//Sin puerto: se utilizará el del listener
+		host = strings.TrimSuffix(strings.TrimPrefix(address, "["), "]")
+		port = ""
+		if strings.Contains(host, ":") && net.ParseIP(host) == nil {
+			fmt.Println("ERROR: Invalid advertise address \"" + address + "\"")
+			os.Exit(1)
+		}
+	}
+	if len(host) == 0 {
+		fmt.Println("ERROR: Missing host in advertise address \"" + address + "\"")
+		os.Exit(1)
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsUnspecified() {
+		fmt.Println("ERROR: Advertise address can't be an unspecified address (" + host + ")")
+		os.Exit(1)
+	}
+	if port != "" {
+		portNumber, portError := strconv.Atoi(port)
+		if portError != nil || portNumber < 1 || portNumber > 65535 {
+			fmt.Println("ERROR: Invalid port in advertise address \"" + address + "\"")
+			os.Exit(1)
+		}
+	}
+	return net.JoinHostPort(host, port)
+}
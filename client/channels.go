@@ -0,0 +1,156 @@
+package client
+
+//Archivo con los canales del servidor, que se identifican por su número (protocolo original) o por su nombre. Los
+//mensajes dirigidos a un canal con nombre indican NAMED_CHANNEL en el header y anteponen al contenido el nombre del
+//canal (1 byte con su longitud seguido del nombre). Las respuestas y las tramas del modo push no llevan el nombre
+
+import (
+	"Client/protocol"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+//Constantes de los canales con nombre
+const NAMED_CHANNEL int8 = -1      //Canal del header de los mensajes dirigidos a un canal con nombre
+const CHANNEL_NAME_MAX_LENGTH = 64 //Longitud máxima del nombre de un canal
+const MAX_CHANNEL_NUMBER = 127     //Número máximo de un canal numérico
+
+//Error que indica que el nombre de un canal no es válido
+var ErrInvalidChannelName = errors.New("invalid channel name")
+
+//Canal del servidor. Los canales con nombre requieren que el servidor los soporte (CAP_NAMED_CHANNELS), salvo que
+//tengan un alias numérico en Client.ChannelAliases
+type Channel struct {
+	Number int8   //Número del canal (0 en los canales con nombre)
+	Name   string //Nombre del canal (vacío en los canales numéricos)
+}
+
+//Función que crea un canal numérico
+func NumberedChannel(number int8) Channel {
+	return Channel{Number: number}
+}
+
+//Función que crea un canal con nombre
+func NamedChannel(name string) Channel {
+	return Channel{Name: name}
+}
+
+//Función que interpreta un canal escrito como número (1-127) o como nombre
+func ParseChannel(text string) (Channel, error) {
+	number, parseError := strconv.Atoi(text)
+	if parseError == nil {
+		if number < 1 || number > MAX_CHANNEL_NUMBER {
+			return Channel{}, fmt.Errorf("channel %d is outside valid range (1-%d)", number, MAX_CHANNEL_NUMBER)
+		}
+		return NumberedChannel(int8(number)), nil
+	}
+	validationError := validateChannelName(text)
+	if validationError != nil {
+		return Channel{}, validationError
+	}
+	return NamedChannel(text), nil
+}
+
+func (ch Channel) String() string {
+	if ch.IsNamed() {
+		return ch.Name
+	}
+	return strconv.Itoa(int(ch.Number))
+}
+
+//Función que indica si el canal se identifica por su nombre
+func (ch Channel) IsNamed() bool {
+	return ch.Name != ""
+}
+
+//Función que valida el nombre de un canal: letras ASCII, dígitos, '-', '_' y '.', empezando por una letra o un dígito
+//(de modo que pueda utilizarse como nombre de directorio) y sin ser solo dígitos (se confundiría con un número)
+func validateChannelName(name string) error {
+	if len(name) == 0 || len(name) > CHANNEL_NAME_MAX_LENGTH {
+		return fmt.Errorf("%w %q (length must be 1-%d)", ErrInvalidChannelName, name, CHANNEL_NAME_MAX_LENGTH)
+	}
+	var onlyDigits bool = true
+	for i, character := range name {
+		var alphanumeric bool = (character >= 'a' && character <= 'z') || (character >= 'A' && character <= 'Z') || (character >= '0' && character <= '9')
+		if !alphanumeric && (i == 0 || (character != '-' && character != '_' && character != '.')) {
+			return fmt.Errorf("%w %q (only letters, digits, '-', '_' and '.' are allowed, starting with a letter or digit)", ErrInvalidChannelName, name)
+		}
+		if character < '0' || character > '9' {
+			onlyDigits = false
+		}
+	}
+	if onlyDigits {
+		return fmt.Errorf("%w %q (names can't be numbers)", ErrInvalidChannelName, name)
+	}
+	return nil
+}
+
+//Función que obtiene el canal que se utiliza en el protocolo: los canales con nombre que tienen un alias numérico se
+//reemplazan por su número
+func (c *Client) resolveChannel(channel Channel) Channel {
+	if number, found := c.ChannelAliases[channel.Name]; channel.IsNamed() && found {
+		return NumberedChannel(number)
+	}
+	return channel
+}
+
+//Función que verifica que el servidor soporte un canal (ya resuelto)
+func checkChannelSupport(channel Channel, capabilities uint32) error {
+	if channel.IsNamed() && capabilities&CAP_NAMED_CHANNELS == 0 {
+		return newError(STATUS_USAGE, "", "Server does not support named channels (channel "+channel.Name+" requires a numeric alias)", nil)
+	}
+	return nil
+}
+
+//Función que obtiene el canal que se indica en el header de los mensajes dirigidos a un canal
+func (ch Channel) headerChannel() int8 {
+	if ch.IsNamed() {
+		return NAMED_CHANNEL
+	}
+	return ch.Number
+}
+
+//Función que crea el campo con el nombre del canal que se antepone al contenido (vacío en los canales numéricos)
+func createChannelNameField(channel Channel) []byte {
+	if !channel.IsNamed() {
+		return nil
+	}
+	return append([]byte{byte(len(channel.Name))}, channel.Name...)
+}
+
+//Función que crea un mensaje dirigido a un canal (ya resuelto)
+func createChannelMessage(command protocol.Command, channel Channel, content []byte) protocol.Message {
+	return protocol.Message{Command: command, Channel: channel.headerChannel(), Content: append(createChannelNameField(channel), content...)}
+}
+
+//Función que lee el campo con el nombre del canal de un mensaje cuyo header indica NAMED_CHANNEL. Retorna el canal y
+//la longitud del campo
+func readChannelNameField(reader io.Reader) (Channel, int64, error) {
+	var lengthBuffer []byte = make([]byte, 1)
+	_, readError := io.ReadFull(reader, lengthBuffer)
+	if readError != nil {
+		return Channel{}, 0, readError
+	}
+	var nameBuffer []byte = make([]byte, lengthBuffer[0])
+	_, readError = io.ReadFull(reader, nameBuffer)
+	if readError != nil {
+		return Channel{}, 0, readError
+	}
+	validationError := validateChannelName(string(nameBuffer))
+	if validationError != nil {
+		return Channel{}, 0, validationError
+	}
+	return NamedChannel(string(nameBuffer)), int64(1 + len(nameBuffer)), nil
+}
+
+//Función que indica si un canal está en una lista de canales
+func containsChannel(channels []Channel, channel Channel) bool {
+	for _, listed := range channels {
+		if listed == channel {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,149 @@
+package client
+
+//Archivo con el envío por bloques, para contenido cuyo tamaño no se conoce de antemano (por ejemplo, la entrada
+//estándar). El mensaje de envío extendido lleva FLAG_CHUNKED y su contenido es solo el campo del nombre; el contenido
+//del archivo se envía a continuación en mensajes STREAM_DATA_COMMAND conforme se lee, y un mensaje STREAM_END_COMMAND
+//(con el hash del archivo, si se envía) indica el final
+
+import (
+	"Client/protocol"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+)
+
+//Tamaño máximo del contenido de un bloque
+const STREAM_CHUNK_SIZE = 64 * 1024
+
+//Error que indica que el contenido no puede enviarse por bloques (el servidor no lo soporta o se cifra el contenido)
+var errChunkedUnsupported = errors.New("chunked transfers not supported")
+
+//Función que envía por bloques un contenido de tamaño desconocido. Si no es posible, retorna errChunkedUnsupported sin
+//haber leído el contenido. El envío no se reintenta, pues el contenido ya leído no puede volver a leerse
+func (c *Client) sendChunked(ctx context.Context, channel Channel, content io.Reader, filename []byte, extraFlags byte) error {
+	//El cifrado de extremo a extremo necesita conocer el tamaño del contenido
+	if c.Passphrase != nil {
+		return errChunkedUnsupported
+	}
+	c.logln("Connecting to server...")
+	connection, capabilities, connectionError := c.connectToServer(ctx, true)
+	if connectionError != nil {
+		return newError(STATUS_COMMUNICATION, "", "Error while connecting to server", connectionError)
+	}
+	c.logln("Connection successful")
+	defer connection.Close()
+	if capabilities&CAP_LONG_FILENAMES == 0 || capabilities&CAP_CHUNKED == 0 {
+		return errChunkedUnsupported
+	}
+	var stopWatching func() = closeOnCancel(ctx, connection)
+	defer stopWatching()
+	supportError := checkChannelSupport(channel, capabilities)
+	if supportError != nil {
+		return supportError
+	}
+	var sendFlags byte = extraFlags | FLAG_CHUNKED
+	if capabilities&CAP_CHECKSUM != 0 {
+		sendFlags |= FLAG_SHA256_TRAILER
+	}
+	//El contenido del mensaje de envío es solo el campo del nombre (precedido por el nombre del canal, si lo tiene)
+	var filenameField []byte = append(createChannelNameField(channel), createFilenameField(protocol.EXTENDED_SEND_COMMAND, sendFlags, filename)...)
+	var encoder *protocol.Encoder = protocol.NewEncoder(connection)
+	messageError := encoder.Encode(protocol.Message{Command: protocol.EXTENDED_SEND_COMMAND, Channel: channel.headerChannel(), Content: filenameField})
+	if messageError != nil {
+		return newError(STATUS_COMMUNICATION, "", "Error while sending message to server", messageError)
+	}
+	//Enviar el contenido por bloques conforme se lee
+	c.logln("Sending content of unknown size in chunks...")
+	var fileHash hash.Hash = sha256.New()
+	var chunk []byte = make([]byte, STREAM_CHUNK_SIZE)
+	var sentLength int64 = 0
+	for {
+		readBytes, readError := io.ReadFull(content, chunk)
+		if readBytes > 0 {
+			fileHash.Write(chunk[:readBytes])
+			sendError := encoder.Encode(protocol.Message{Command: protocol.STREAM_DATA_COMMAND, Channel: channel.headerChannel(), Content: chunk[:readBytes]})
+			if sendError != nil {
+				return newError(STATUS_COMMUNICATION, "", "Error while sending file contents", sendError)
+			}
+			sentLength += int64(readBytes)
+		}
+		if readError == io.EOF || readError == io.ErrUnexpectedEOF {
+			break
+		} else if readError != nil {
+			return newError(STATUS_FILESYSTEM, "", "Error while reading file contents", readError)
+		}
+	}
+	var trailer []byte
+	if sendFlags&FLAG_SHA256_TRAILER != 0 {
+		trailer = fileHash.Sum(nil)
+	}
+	endError := encoder.Encode(protocol.Message{Command: protocol.STREAM_END_COMMAND, Channel: channel.headerChannel(), Content: trailer})
+	if endError != nil {
+		return newError(STATUS_COMMUNICATION, "", "Error while sending file contents", endError)
+	}
+	c.logf("File sent (%d bytes). Awaiting server response...\n", sentLength)
+	response, responseError := readServerResponse(connection)
+	if responseError != nil {
+		return responseError
+	}
+	switch response.Command {
+	case protocol.OK_COMMAND:
+		c.logln("Server received file successfully. It will be sent to all subscribed clients on selected channel.")
+		return nil
+	case protocol.ERROR_COMMAND:
+		return newError(STATUS_COMMUNICATION, "", "", &ServerError{string(response.Content)})
+	default:
+		return newError(STATUS_COMMUNICATION, "", fmt.Sprint("Invalid command received from server: ", response.Command), nil)
+	}
+}
+
+//Reader del contenido de un archivo recibido por bloques. Al llegar al final (io.EOF) se tiene el hash enviado tras el
+//último bloque
+type chunkedReader struct {
+	decoder   *protocol.Decoder
+	reader    io.Reader
+	remaining int64  //Bytes que faltan leer del bloque actual
+	trailer   []byte //Contenido del mensaje que indica el final (nil mientras no llega)
+	maxLength int64  //Longitud máxima del hash enviado al final
+}
+
+func (r *chunkedReader) Read(p []byte) (int, error) {
+	for r.remaining == 0 {
+		if r.trailer != nil {
+			return 0, io.EOF
+		}
+		header, headerError := r.decoder.DecodeHeader()
+		if headerError == io.EOF {
+			return 0, io.ErrUnexpectedEOF
+		} else if headerError != nil {
+			return 0, headerError
+		}
+		switch header.Command {
+		case protocol.STREAM_DATA_COMMAND:
+			r.remaining = header.Length
+		case protocol.STREAM_END_COMMAND:
+			message, trailerError := r.decoder.DecodeContent(header, r.maxLength)
+			if trailerError != nil {
+				return 0, trailerError
+			}
+			r.trailer = append([]byte{}, message.Content...)
+		default:
+			return 0, fmt.Errorf("invalid command %v in chunked transfer", header.Command)
+		}
+	}
+	if int64(len(p)) > r.remaining {
+		p = p[:r.remaining]
+	}
+	n, readError := r.reader.Read(p)
+	r.remaining -= int64(n)
+	if readError == io.EOF {
+		readError = io.ErrUnexpectedEOF
+	}
+	if n > 0 {
+		return n, nil
+	}
+	return 0, readError
+}
@@ -0,0 +1,154 @@
+package client
+
+//Paquete con la implementación del cliente de intercambio de archivos: envío de archivos a un canal y suscripción a
+//canales para recibir los archivos que envían otros clientes, comunicándose con un servidor TCP a través de un
+//protocolo personalizado. El cliente de línea de comandos es una capa delgada sobre este paquete
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+//Constantes
+const BUFFER_SIZE = 1024                                  //Tamaño de un buffer temporal utilizado para leer archivos iterativamente
+const SERVER_PORT = "7101"                                //Puerto en el que opera el servidor
+const DEFAULT_SERVER_ADDRESS = "127.0.0.1:" + SERVER_PORT //Dirección del servidor si no se configura otra
+const DEFAULT_LISTEN_ADDRESS = "127.0.0.1:0"              //Dirección por defecto del listener de recepción (puerto aleatorio)
+const FILENAME_MAX_LENGTH = 40                            //Tamaño máximo del nombre de un archivo que se recibe
+const LONG_FILENAME_MAX_LENGTH = 255                      //Tamaño máximo del nombre de un archivo en el formato de envío extendido
+const PARTIAL_FILE_SUFFIX = ".partial"                    //Sufijo de los archivos que aún se están recibiendo
+
+//En el formato de envío extendido, el contenido inicia con un byte de flags y el nombre del archivo prefijado por su
+//longitud (2 bytes), en vez del campo de longitud fija del comando send
+const EXTENDED_FILENAME_HEADER_LENGTH = 3 //Bytes que preceden al nombre en el formato extendido (flags + longitud)
+
+//Flags del formato de envío extendido
+const FLAG_SHA256_TRAILER byte = 1 << 0 //El contenido termina con el hash SHA-256 del archivo (32 bytes)
+const FLAG_RESUMABLE byte = 1 << 1      //Después del nombre se envían los datos de reanudación (ver resume.go)
+const FLAG_ENCRYPTED byte = 1 << 2      //Contenido cifrado de extremo a extremo; tras el nombre van los datos de cifrado (ver encryption.go)
+const FLAG_RELATIVE_PATH byte = 1 << 3  //El nombre es una ruta relativa con '/' como separador (archivo de un directorio, ver directory.go)
+const FLAG_MANIFEST byte = 1 << 4       //El contenido es el manifiesto de un directorio enviado (ver directory.go)
+const FLAG_CHUNKED byte = 1 << 5        //El contenido se envía por bloques en los mensajes que siguen (ver chunked.go)
+const FLAG_METADATA byte = 1 << 6       //Después del nombre se envían los metadatos del archivo (ver metadata.go)
+
+//Flags del formato de envío extendido que el cliente sabe interpretar
+const SUPPORTED_SEND_FLAGS byte = FLAG_SHA256_TRAILER | FLAG_RESUMABLE | FLAG_ENCRYPTED | FLAG_RELATIVE_PATH | FLAG_MANIFEST | FLAG_CHUNKED | FLAG_METADATA
+
+//Cliente del servidor de intercambio de archivos. Un Client sin configurar se conecta a un servidor local, sin TLS ni
+//cifrado de extremo a extremo. Sus métodos pueden utilizarse desde varias goroutines a la vez, pero su configuración no
+//debe modificarse mientras se utiliza
+type Client struct {
+	Server            string          //Dirección (host:puerto) del servidor (por defecto DEFAULT_SERVER_ADDRESS)
+	TLSConfig         *tls.Config     //Configuración de TLS para las conexiones con el servidor (nil si no se utiliza TLS)
+	ListenerTLSConfig *tls.Config     //Configuración de TLS del listener que recibe las conexiones del servidor (nil si no se utiliza TLS)
+	ListenAddress     string          //Dirección del listener de recepción (por defecto DEFAULT_LISTEN_ADDRESS)
+	Advertise         string          //Dirección (host[:puerto]) que se anuncia al servidor para conectarse al listener (vacía para usar la del listener)
+	Push              bool            //Recibir los archivos por la conexión de suscripción en vez de un listener (ver push.go)
+	Passphrase        []byte          //Frase secreta del canal para cifrar y descifrar el contenido de extremo a extremo (nil si no se cifra)
+	Retries           int             //Cantidad de veces que se reanuda un envío si se cae la conexión
+	ConnectTimeout    time.Duration   //Tiempo máximo para conectarse al servidor (0: DEFAULT_CONNECT_TIMEOUT, negativo: sin límite)
+	ReadTimeout       time.Duration   //Tiempo máximo sin recibir datos de una conexión (0: DEFAULT_IDLE_TIMEOUT, negativo: sin límite)
+	WriteTimeout      time.Duration   //Tiempo máximo sin poder enviar datos por una conexión (0: DEFAULT_IDLE_TIMEOUT, negativo: sin límite)
+	TransferTimeout   time.Duration   //Tiempo máximo de la transferencia de un archivo (0 o negativo: sin límite)
+	ChannelAliases    map[string]int8 //Números de los canales con nombre, para usarlos con servidores que no soportan canales con nombre
+	GracePeriod       time.Duration   //Tiempo que se espera a que terminen las transferencias en curso al terminar una suscripción (0: se interrumpen de inmediato, negativo: sin límite)
+	Log               io.Writer       //Destino de los mensajes de progreso (nil para descartarlos)
+
+	legacyServer       int32 //Indica si ya se detectó que el servidor no soporta el saludo (ver handshake.go)
+	subscriptionsMutex sync.Mutex
+	subscriptions      map[Channel]*subscription //Suscripciones activas, por canal (ya resuelto)
+}
+
+//Función que escribe un mensaje de progreso en el destino configurado
+func (c *Client) logf(format string, arguments ...interface{}) {
+	if c.Log != nil {
+		fmt.Fprintf(c.Log, format, arguments...)
+	}
+}
+
+//Función que escribe un mensaje de progreso (con salto de línea) en el destino configurado
+func (c *Client) logln(arguments ...interface{}) {
+	if c.Log != nil {
+		fmt.Fprintln(c.Log, arguments...)
+	}
+}
+
+//Función que obtiene la dirección del servidor configurada (o la dirección por defecto)
+func (c *Client) serverAddress() string {
+	if c.Server == "" {
+		return DEFAULT_SERVER_ADDRESS
+	}
+	return c.Server
+}
+
+//Función que establece una conexión TCP con la dirección indicada, utilizando TLS si está configurado. La conexión
+//aplica los tiempos máximos de espera del cliente (incluyendo el de la transferencia completa si transfer es true)
+func (c *Client) dial(ctx context.Context, address string, transfer bool) (net.Conn, error) {
+	var connectTimeout time.Duration = timeoutOrDefault(c.ConnectTimeout, DEFAULT_CONNECT_TIMEOUT)
+	var dialer *net.Dialer = &net.Dialer{Timeout: connectTimeout}
+	rawConnection, dialError := dialer.DialContext(ctx, "tcp", address)
+	if dialError != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if netError, ok := dialError.(net.Error); ok && netError.Timeout() {
+			return nil, &TimeoutError{Kind: TIMEOUT_CONNECT, Limit: connectTimeout}
+		}
+		return nil, dialError
+	}
+	var connection *timeoutConn = c.withTimeouts(rawConnection, transfer)
+	if c.TLSConfig == nil {
+		return connection, nil
+	}
+	//Sin un nombre configurado, se verifica el certificado del servidor con el host de la dirección (como tls.Dial)
+	var config *tls.Config = c.TLSConfig
+	if config.ServerName == "" {
+		host, _, _ := net.SplitHostPort(address)
+		config = config.Clone()
+		config.ServerName = host
+	}
+	//El saludo de TLS también debe completarse antes del tiempo máximo de conexión (si tiene límite)
+	var tlsConnection *tls.Conn = tls.Client(connection, config)
+	if connectTimeout > 0 {
+		connection.SetDeadline(time.Now().Add(connectTimeout))
+	}
+	handshakeError := tlsConnection.HandshakeContext(ctx)
+	if connectTimeout > 0 {
+		connection.SetDeadline(time.Time{})
+	}
+	if handshakeError != nil {
+		tlsConnection.Close()
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if errors.Is(handshakeError, os.ErrDeadlineExceeded) {
+			return nil, &TimeoutError{Kind: TIMEOUT_CONNECT, Limit: connectTimeout}
+		}
+		return nil, handshakeError
+	}
+	return tlsConnection, nil
+}
+
+//Función que crea el listener para las conexiones del servidor, utilizando TLS si está configurado
+func (c *Client) listen() (net.Listener, error) {
+	var address string = c.ListenAddress
+	if address == "" {
+		address = DEFAULT_LISTEN_ADDRESS
+	}
+	rawListener, listenerError := net.Listen("tcp", address)
+	if listenerError != nil {
+		return nil, listenerError
+	}
+	var listener net.Listener = &timeoutListener{Listener: rawListener, client: c}
+	if c.ListenerTLSConfig == nil {
+		return listener, nil
+	}
+	return tls.NewListener(listener, c.ListenerTLSConfig), nil
+}
@@ -0,0 +1,211 @@
+package client
+
+//Archivo con las funciones para enviar un directorio completo. Cada archivo se envía con su ruta relativa (empezando por
+//el nombre del directorio) y FLAG_RELATIVE_PATH; al final se envía el manifiesto del directorio (FLAG_MANIFEST), con
+//todos sus archivos y subdirectorios (incluidos los vacíos), de modo que el receptor sepa cuándo lo recibió completo
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+//Tamaño máximo del manifiesto de un directorio
+const MAX_MANIFEST_LENGTH = 16 * 1024 * 1024
+
+//Manifiesto de un directorio enviado con SendDirectory. Las rutas son relativas (con '/' como separador) y empiezan con
+//el nombre del directorio
+type Manifest struct {
+	Root        string         `json:"root"`        //Nombre del directorio
+	Files       []ManifestFile `json:"files"`       //Archivos del directorio
+	Directories []string       `json:"directories"` //Subdirectorios del directorio, incluidos los vacíos
+}
+
+//Archivo de un directorio enviado
+type ManifestFile struct {
+	Path string `json:"path"` //Ruta relativa del archivo
+	Size int64  `json:"size"` //Tamaño del archivo
+}
+
+//Función para enviar un directorio y todo su contenido a un canal, archivo por archivo y seguido de su manifiesto. Los
+//enlaces simbólicos y archivos especiales se omiten. Requiere que el servidor soporte el formato de envío extendido
+func (c *Client) SendDirectory(ctx context.Context, channel Channel, directory string) error {
+	manifest, scanError := c.scanDirectory(directory)
+	if scanError != nil {
+		return scanError
+	}
+	for i, file := range manifest.Files {
+		c.logf("Sending file %d of %d: %s\n", i+1, len(manifest.Files), file.Path)
+		sendError := c.sendDirectoryFile(ctx, channel, directory, manifest.Root, file.Path)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if sendError != nil {
+			var clientError *Error
+			var status int = STATUS_COMMUNICATION
+			if errors.As(sendError, &clientError) {
+				status = clientError.Status
+			}
+			return newError(status, "", "Error while sending file "+file.Path, sendError)
+		}
+	}
+	content, encodeError := json.Marshal(manifest)
+	if encodeError != nil {
+		return newError(STATUS_PROTOCOL, "", "Error while creating directory manifest", encodeError)
+	}
+	if len(content) > MAX_MANIFEST_LENGTH {
+		return newError(STATUS_USAGE, "", "Directory "+directory+" has too many files to send at once", nil)
+	}
+	c.logln("Sending manifest of directory " + manifest.Root + "...")
+	return c.send(ctx, channel, bytes.NewReader(content), manifest.Root, FLAG_MANIFEST)
+}
+
+//Función que envía un archivo de un directorio con su ruta relativa
+func (c *Client) sendDirectoryFile(ctx context.Context, channel Channel, directory string, root string, path string) error {
+	file, openError := os.Open(filepath.Join(directory, filepath.FromSlash(strings.TrimPrefix(path, root+"/"))))
+	if openError != nil {
+		return newError(STATUS_FILESYSTEM, "", "Error while opening file", openError)
+	}
+	defer file.Close()
+	return c.send(ctx, channel, file, path, FLAG_RELATIVE_PATH)
+}
+
+//Función que recorre un directorio y crea su manifiesto, verificando que las rutas de todos sus archivos puedan enviarse
+func (c *Client) scanDirectory(directory string) (*Manifest, error) {
+	absolutePath, pathError := filepath.Abs(directory)
+	if pathError != nil {
+		return nil, newError(STATUS_FILESYSTEM, "", "Error while opening directory", pathError)
+	}
+	var manifest *Manifest = &Manifest{Root: filepath.Base(absolutePath), Files: []ManifestFile{}, Directories: []string{}}
+	if len(manifest.Root) > LONG_FILENAME_MAX_LENGTH || manifest.Root == string(filepath.Separator) {
+		return nil, newError(STATUS_USAGE, "", "Directory "+directory+" can't be sent", nil)
+	}
+	walkError := filepath.WalkDir(directory, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		relativePath, relativeError := filepath.Rel(directory, path)
+		if relativeError != nil {
+			return relativeError
+		}
+		if relativePath == "." {
+			return nil
+		}
+		relativePath = manifest.Root + "/" + filepath.ToSlash(relativePath)
+		if len(relativePath) > LONG_FILENAME_MAX_LENGTH {
+			return fmt.Errorf("path %s is too long (max length: %d bytes)", relativePath, LONG_FILENAME_MAX_LENGTH)
+		}
+		if entry.IsDir() {
+			manifest.Directories = append(manifest.Directories, relativePath)
+			return nil
+		}
+		if !entry.Type().IsRegular() {
+			c.logln("WARNING: Skipping " + path + " (not a regular file)")
+			return nil
+		}
+		info, infoError := entry.Info()
+		if infoError != nil {
+			return infoError
+		}
+		manifest.Files = append(manifest.Files, ManifestFile{Path: relativePath, Size: info.Size()})
+		return nil
+	})
+	if walkError != nil {
+		return nil, newError(STATUS_FILESYSTEM, "", "Error while reading directory "+directory, walkError)
+	}
+	return manifest, nil
+}
+
+//Función que lee y valida el manifiesto de un directorio recibido (un archivo con Manifest en true). Las rutas del
+//manifiesto se sanean igual que los nombres de los archivos recibidos, por lo que corresponden a los de IncomingFile
+func ReadManifest(file *IncomingFile) (*Manifest, error) {
+	if !file.Manifest {
+		return nil, newError(STATUS_USAGE, "", "File "+file.Name+" is not a directory manifest", nil)
+	}
+	if file.Size > MAX_MANIFEST_LENGTH {
+		return nil, newError(STATUS_PROTOCOL, "invalid manifest", "Manifest of directory "+file.Name+" is too long", nil)
+	}
+	var content bytes.Buffer
+	_, readError := content.ReadFrom(file)
+	if readError != nil {
+		return nil, readError
+	}
+	var manifest *Manifest = &Manifest{}
+	decodeError := json.Unmarshal(content.Bytes(), manifest)
+	if decodeError != nil {
+		return nil, newError(STATUS_PROTOCOL, "invalid manifest", "Invalid manifest of directory "+file.Name, decodeError)
+	}
+	root, rootError := sanitizeFilename(manifest.Root)
+	if rootError != nil || root != file.Name {
+		return nil, newError(STATUS_PROTOCOL, "invalid manifest", "Invalid manifest of directory "+file.Name+" (root doesn't match)", rootError)
+	}
+	manifest.Root = root
+	for i := range manifest.Files {
+		var pathError error
+		manifest.Files[i].Path, pathError = sanitizeManifestPath(root, manifest.Files[i].Path)
+		if pathError != nil {
+			return nil, newError(STATUS_PROTOCOL, "invalid manifest", "Invalid manifest of directory "+file.Name, pathError)
+		}
+	}
+	for i := range manifest.Directories {
+		var pathError error
+		manifest.Directories[i], pathError = sanitizeManifestPath(root, manifest.Directories[i])
+		if pathError != nil {
+			return nil, newError(STATUS_PROTOCOL, "invalid manifest", "Invalid manifest of directory "+file.Name, pathError)
+		}
+	}
+	return manifest, nil
+}
+
+//Función que sanea una ruta del manifiesto, que debe estar dentro del directorio
+func sanitizeManifestPath(root string, path string) (string, error) {
+	sanitized, sanitizeError := sanitizeRelativePath(path)
+	if sanitizeError != nil {
+		return "", fmt.Errorf("path %q: %w", path, sanitizeError)
+	}
+	if !strings.HasPrefix(sanitized, root+"/") {
+		return "", fmt.Errorf("path %q is outside of directory %s", path, root)
+	}
+	return sanitized, nil
+}
+
+//Función que separa una ruta relativa en su directorio (vacío si no tiene) y su nombre
+func splitRelativePath(path string) (string, string) {
+	var separator int = strings.LastIndexByte(path, '/')
+	if separator == -1 {
+		return "", path
+	}
+	return path[:separator], path[separator+1:]
+}
+
+//Función que crea (si no existen) los subdirectorios de una ruta relativa ya saneada dentro del directorio de descarga
+//(terminado con un separador). No se siguen enlaces simbólicos, de modo que un enlace existente no permita escribir
+//fuera del directorio de descarga. Retorna la ruta del último subdirectorio terminada con un separador
+func createRelativeDirectory(downloadPath string, directory string) (string, error) {
+	var path string = downloadPath
+	if directory == "" {
+		return path, nil
+	}
+	for _, component := range strings.Split(directory, "/") {
+		path += component
+		mkdirError := os.Mkdir(path, 0777)
+		if mkdirError != nil && !os.IsExist(mkdirError) {
+			return "", mkdirError
+		}
+		fileInfo, statError := os.Lstat(path)
+		if statError != nil {
+			return "", statError
+		}
+		if !fileInfo.IsDir() {
+			return "", errors.New(path + " already exists and is not a directory")
+		}
+		path += string(os.PathSeparator)
+	}
+	return path, nil
+}
@@ -0,0 +1,437 @@
+package client
+
+//Archivo con el handler que guarda en un directorio los archivos recibidos
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+//Políticas posibles ante un archivo recibido cuyo nombre ya existe en el directorio de descarga
+const CONFLICT_OVERWRITE = "overwrite" //Se reemplaza el archivo existente
+const CONFLICT_SKIP = "skip"           //Se descarta el archivo recibido y se responde con un error al servidor
+const CONFLICT_RENAME = "rename"       //Se guarda con un sufijo numérico (archivo (1).txt)
+const CONFLICT_TIMESTAMP = "timestamp" //Se guarda con la fecha y hora de recepción (archivo_20060102-150405.txt)
+
+//Sufijo del archivo con los datos de una transferencia reanudable
+const PARTIAL_METADATA_SUFFIX = ".meta"
+
+//Mutex para elegir el nombre final de los archivos recibidos sin condiciones de carrera
+var finalizeMutex sync.Mutex
+
+//Handler que guarda los archivos recibidos en un directorio. El contenido se escribe a disco conforme va llegando en un
+//archivo parcial oculto, que solo se renombra al nombre real una vez recibido y verificado el archivo completo. Los
+//archivos parciales de las transferencias reanudables se conservan si la transferencia se interrumpe
+type DiskHandler struct {
+	Path               string    //Directorio en el que se guardan los archivos recibidos
+	ConflictPolicy     string    //Política a aplicar cuando ya existe un archivo con el mismo nombre (por defecto CONFLICT_OVERWRITE)
+	ChannelDirectories bool      //Guardar los archivos de cada canal en un subdirectorio de Path con el número o nombre del canal
+	IgnoreMetadata     bool      //No aplicar la fecha de modificación y los permisos enviados por el remitente
+	Log                io.Writer //Destino de los mensajes de progreso (nil para descartarlos)
+
+	activeTransfersMutex sync.Mutex
+	activeTransfers      map[string]bool //Transferencias reanudables que se están recibiendo (para no escribir el mismo archivo parcial en paralelo)
+	receivedFilesMutex   sync.Mutex
+	receivedFiles        map[string]bool //Archivos de directorios recibidos cuyo manifiesto aún no llega
+}
+
+//Datos de una transferencia reanudable que se guardan junto al archivo parcial
+type partialMetadata struct {
+	Filename string `json:"filename"`
+	Size     int64  `json:"size"`
+}
+
+//Función que obtiene el directorio de descarga de un canal terminado con un separador
+func (h *DiskHandler) downloadPath(channel Channel) string {
+	var downloadPath string = h.Path
+	if !strings.HasSuffix(downloadPath, string(os.PathSeparator)) {
+		downloadPath += string(os.PathSeparator)
+	}
+	if h.ChannelDirectories {
+		downloadPath += channel.String() + string(os.PathSeparator)
+	}
+	return downloadPath
+}
+
+//Función que guarda un archivo recibido en el directorio de descarga. Si se cancela el contexto, se elimina el archivo
+//parcial (aunque la transferencia sea reanudable)
+func (h *DiskHandler) ReceiveFile(ctx context.Context, incoming *IncomingFile) error {
+	var channelPath string = h.downloadPath(incoming.Channel)
+	//El subdirectorio de cada canal se crea al recibir su primer archivo
+	if h.ChannelDirectories {
+		mkdirError := os.MkdirAll(channelPath, 0777)
+		if mkdirError != nil {
+			return newError(STATUS_FILESYSTEM, "directory creation failed", "Error while creating download directory of channel "+incoming.Channel.String(), mkdirError)
+		}
+	}
+	if incoming.Manifest {
+		return h.receiveManifest(channelPath, incoming)
+	}
+	//Los archivos de un directorio se guardan en su ruta relativa dentro del directorio de descarga
+	directory, filename := splitRelativePath(incoming.Name)
+	downloadPath, directoryError := createRelativeDirectory(channelPath, directory)
+	if directoryError != nil {
+		return newError(STATUS_FILESYSTEM, "directory creation failed", "Error while creating directory "+directory, directoryError)
+	}
+	//Si se deben conservar los archivos existentes, se rechaza la transferencia antes de recibir el contenido
+	if h.ConflictPolicy == CONFLICT_SKIP && fileExists(downloadPath+filename) {
+		return newError(STATUS_PROTOCOL, "file already exists", "Skipping transfer of file "+filename, ErrFileExists)
+	}
+	//No se puede recibir la misma transferencia dos veces a la vez
+	var resumable bool = incoming.TransferID != nil
+	if resumable {
+		if !h.acquireTransfer(incoming.TransferID) {
+			return newError(STATUS_PROTOCOL, "transfer already in progress", "Transfer of file "+filename+" is already in progress", nil)
+		}
+		defer h.releaseTransfer(incoming.TransferID)
+	}
+	//Se crea un archivo parcial (oculto) en el directorio de descarga, de modo que el contenido se escriba a disco
+	//conforme va llegando sin que aparezca un archivo truncado con el nombre real. Este solo se renombra una vez que
+	//el archivo se recibió completamente. Las transferencias reanudables usan un archivo parcial asociado a su
+	//identificador, que se conserva si la transferencia se interrumpe
+	var file *os.File
+	var fileError error
+	if resumable {
+		file, fileError = openResumablePartialFile(downloadPath, incoming)
+	} else {
+		file, fileError = createPartialFile(downloadPath)
+	}
+	//Si no se tienen los bytes previos al byte inicial, se pide al servidor continuar desde el último que se tiene
+	var offsetError *ResumeOffsetError
+	if errors.As(fileError, &offsetError) {
+		return fileError
+	}
+	//Error check
+	if fileError != nil {
+		return newError(STATUS_FILESYSTEM, "file creation failed", "Error while creating received file in filesystem", fileError)
+	}
+	var partialPath string = file.Name()
+	//Si la transferencia no concluye correctamente, se elimina el archivo parcial (salvo que sea reanudable y su
+	//contenido siga siendo válido)
+	var completed bool = false
+	var keepPartial bool = resumable
+	defer func() {
+		if !completed {
+			file.Close()
+			if !keepPartial {
+				os.Remove(partialPath)
+				removePartialMetadata(partialPath)
+			}
+		}
+	}()
+	//Leer el resto del mensaje (contenido del archivo) y volcarlo al archivo creado de forma iterativa
+	var tempBuffer []byte = make([]byte, BUFFER_SIZE)
+	for {
+		n, readError := incoming.Read(tempBuffer)
+		if n > 0 {
+			_, writeError := file.Write(tempBuffer[:n])
+			//Error check
+			if writeError != nil {
+				return newError(STATUS_FILESYSTEM, "file writing failed", "Error while writing file to filesystem", writeError)
+			}
+		}
+		//Error check
+		if readError == io.EOF { //Se concluyó la lectura (y se verificó el contenido)
+			break
+		} else if readError != nil {
+			//La transferencia se canceló (y se cerró la conexión)
+			if ctx.Err() != nil {
+				keepPartial = false
+				return ctx.Err()
+			}
+			//El contenido recibido no es válido, por lo que no tiene sentido conservarlo para reanudar
+			if errors.Is(readError, ErrChecksumMismatch) || errors.Is(readError, ErrDecryptionFailed) {
+				keepPartial = false
+			}
+			return readError
+		}
+	}
+	//Asegurarse de que el contenido llegue a disco antes de colocar el archivo en su lugar
+	syncError := file.Sync()
+	if syncError == nil {
+		syncError = file.Close()
+	}
+	//Error check
+	if syncError != nil {
+		return newError(STATUS_FILESYSTEM, "file writing failed", "Error while flushing received file to filesystem", syncError)
+	}
+	//Los metadatos se aplican al archivo parcial, de modo que el archivo aparezca con ellos
+	if incoming.Metadata != nil && !h.IgnoreMetadata {
+		metadataError := applyFileMetadata(partialPath, incoming.Metadata)
+		if metadataError != nil {
+			return newError(STATUS_FILESYSTEM, "file writing failed", "Error while applying metadata of received file", metadataError)
+		}
+	}
+	//Renombrar el archivo parcial al nombre real del archivo (según la política de conflictos elegida)
+	savedName, renameError := finalizeReceivedFile(partialPath, downloadPath, filename, h.ConflictPolicy)
+	//Error check
+	if renameError == ErrFileExists {
+		return newError(STATUS_PROTOCOL, "file already exists", "Discarding received file "+filename, ErrFileExists)
+	} else if renameError != nil {
+		return newError(STATUS_FILESYSTEM, "file creation failed", "Error while moving received file into place", renameError)
+	}
+	if savedName != filename && h.Log != nil {
+		fmt.Fprintln(h.Log, "File "+filename+" already exists, saved as "+savedName)
+	}
+	completed = true
+	removePartialMetadata(partialPath)
+	if directory != "" {
+		h.markReceived(channelPath + incoming.Name)
+	}
+	return nil
+}
+
+//Función que procesa el manifiesto de un directorio recibido: crea sus subdirectorios (incluidos los vacíos) y verifica
+//que se hayan recibido todos sus archivos. Si falta alguno, se responde con un error al servidor
+func (h *DiskHandler) receiveManifest(channelPath string, incoming *IncomingFile) error {
+	manifest, manifestError := ReadManifest(incoming)
+	if manifestError != nil {
+		return manifestError
+	}
+	for _, directory := range manifest.Directories {
+		_, directoryError := createRelativeDirectory(channelPath, directory)
+		if directoryError != nil {
+			return newError(STATUS_FILESYSTEM, "directory creation failed", "Error while creating directory "+directory, directoryError)
+		}
+	}
+	//Los archivos que no se recibieron durante esta ejecución del handler pueden haberse recibido antes
+	var missing []string
+	for _, file := range manifest.Files {
+		if h.takeReceived(channelPath + file.Path) {
+			continue
+		}
+		fileInfo, statError := os.Lstat(channelPath + filepath.FromSlash(file.Path))
+		if statError != nil || !fileInfo.Mode().IsRegular() || fileInfo.Size() != file.Size {
+			missing = append(missing, file.Path)
+		}
+	}
+	if len(missing) > 0 {
+		return newError(STATUS_PROTOCOL, "directory incomplete", fmt.Sprintf("Directory %v is incomplete (%d of %d files missing: %v)", manifest.Root, len(missing), len(manifest.Files), strings.Join(missing, ", ")), nil)
+	}
+	if h.Log != nil {
+		fmt.Fprintf(h.Log, "Directory %v received completely (%d files, %d directories)\n", manifest.Root, len(manifest.Files), len(manifest.Directories))
+	}
+	return nil
+}
+
+//Función que registra un archivo de un directorio como recibido, hasta que llegue el manifiesto del directorio
+func (h *DiskHandler) markReceived(path string) {
+	h.receivedFilesMutex.Lock()
+	defer h.receivedFilesMutex.Unlock()
+	if h.receivedFiles == nil {
+		h.receivedFiles = map[string]bool{}
+	}
+	h.receivedFiles[path] = true
+}
+
+//Función que indica si se recibió un archivo de un directorio y deja de registrarlo
+func (h *DiskHandler) takeReceived(path string) bool {
+	h.receivedFilesMutex.Lock()
+	defer h.receivedFilesMutex.Unlock()
+	var received bool = h.receivedFiles[path]
+	delete(h.receivedFiles, path)
+	return received
+}
+
+//Función que crea un archivo parcial oculto y único para recibir un archivo. Su nombre es solo un identificador
+//aleatorio (como el de las transferencias reanudables), de modo que no supere el largo máximo de un nombre aunque el
+//del archivo recibido ya esté en el límite
+func createPartialFile(downloadPath string) (*os.File, error) {
+	var randomBuffer []byte = make([]byte, 6)
+	for {
+		//Generar un identificador aleatorio para evitar colisiones entre transferencias simultáneas
+		_, randomError := rand.Read(randomBuffer)
+		if randomError != nil {
+			return nil, randomError
+		}
+		var partialPath string = downloadPath + "." + hex.EncodeToString(randomBuffer) + PARTIAL_FILE_SUFFIX
+		//Se crea con los mismos permisos que os.Create, fallando si el archivo ya existe
+		file, fileError := os.OpenFile(partialPath, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0666)
+		if os.IsExist(fileError) {
+			continue
+		}
+		return file, fileError
+	}
+}
+
+//Función que mueve un archivo parcial ya recibido a su ubicación final, aplicando la política de conflictos de nombres.
+//Retorna el nombre con el que se guardó el archivo
+func finalizeReceivedFile(partialPath string, downloadPath string, filename string, conflictPolicy string) (string, error) {
+	//Varias goroutines pueden estar recibiendo archivos con el mismo nombre, por lo que la elección del nombre final y
+	//el renombrado se realizan de forma exclusiva
+	finalizeMutex.Lock()
+	defer finalizeMutex.Unlock()
+	var savedName string = filename
+	switch conflictPolicy {
+	case CONFLICT_SKIP:
+		if fileExists(downloadPath + savedName) {
+			return "", ErrFileExists
+		}
+	case CONFLICT_RENAME:
+		savedName = availableFilename(downloadPath, filename)
+	case CONFLICT_TIMESTAMP:
+		if fileExists(downloadPath + savedName) {
+			savedName = suffixedFilename(filename, "_"+time.Now().Format("20060102-150405"))
+			savedName = availableFilename(downloadPath, savedName)
+		}
+	}
+	return savedName, os.Rename(partialPath, downloadPath+savedName)
+}
+
+//Función que retorna un nombre de archivo que no exista en el directorio, agregando un sufijo numérico si es necesario
+func availableFilename(downloadPath string, filename string) string {
+	var candidate string = filename
+	for i := 1; fileExists(downloadPath + candidate); i++ {
+		candidate = suffixedFilename(filename, " ("+strconv.Itoa(i)+")")
+	}
+	return candidate
+}
+
+//Función que agrega un sufijo al nombre de un archivo (antes de su extensión). Si el resultado supera
+//LONG_FILENAME_MAX_LENGTH bytes (el largo máximo de un nombre en la mayoría de los sistemas de archivos), se acorta el
+//nombre sin cortar un caracter a la mitad
+func suffixedFilename(filename string, suffix string) string {
+	var extension string = filepath.Ext(filename)
+	var base string = strings.TrimSuffix(filename, extension)
+	//Una extensión muy larga se acorta junto con el resto del nombre
+	if len(extension)+len(suffix) > LONG_FILENAME_MAX_LENGTH/2 {
+		base = filename
+		extension = ""
+	}
+	var cut int = LONG_FILENAME_MAX_LENGTH - len(suffix) - len(extension)
+	if len(base) > cut {
+		for cut > 0 && !utf8.RuneStart(base[cut]) {
+			cut--
+		}
+		base = base[:cut]
+	}
+	return base + suffix + extension
+}
+
+//Función que indica si ya existe un archivo (o directorio) en la ruta indicada
+func fileExists(path string) bool {
+	_, err := os.Lstat(path)
+	return !os.IsNotExist(err)
+}
+
+//Función que obtiene la ruta del archivo parcial de una transferencia reanudable
+func resumablePartialPath(downloadPath string, transferID []byte) string {
+	return downloadPath + "." + hex.EncodeToString(transferID) + PARTIAL_FILE_SUFFIX
+}
+
+//Función que marca una transferencia reanudable como activa. Retorna false si ya se está recibiendo
+func (h *DiskHandler) acquireTransfer(transferID []byte) bool {
+	h.activeTransfersMutex.Lock()
+	defer h.activeTransfersMutex.Unlock()
+	var key string = hex.EncodeToString(transferID)
+	if h.activeTransfers[key] {
+		return false
+	}
+	if h.activeTransfers == nil {
+		h.activeTransfers = map[string]bool{}
+	}
+	h.activeTransfers[key] = true
+	return true
+}
+
+//Función que marca una transferencia reanudable como terminada
+func (h *DiskHandler) releaseTransfer(transferID []byte) {
+	h.activeTransfersMutex.Lock()
+	defer h.activeTransfersMutex.Unlock()
+	delete(h.activeTransfers, hex.EncodeToString(transferID))
+}
+
+//Función que abre el archivo parcial de una transferencia reanudable, listo para escribir a partir del byte inicial de
+//la entrega. Los bytes que ya se tenían se añaden al hash del archivo. Si el archivo parcial no tiene suficientes
+//bytes, se retorna un *ResumeOffsetError con la cantidad que sí tiene
+func openResumablePartialFile(downloadPath string, incoming *IncomingFile) (*os.File, error) {
+	var partialPath string = resumablePartialPath(downloadPath, incoming.TransferID)
+	var metadata partialMetadata = partialMetadata{incoming.Name, incoming.Size}
+	//Si ya se tiene parte del archivo de una entrega anterior, se pide continuar desde ahí en vez de recibirlo de nuevo
+	storedMetadata, metadataError := readPartialMetadata(partialPath)
+	if incoming.Offset == 0 && metadataError == nil && storedMetadata == metadata {
+		fileInfo, statError := os.Stat(partialPath)
+		if statError == nil && fileInfo.Size() > 0 && fileInfo.Size() < incoming.Size {
+			return nil, &ResumeOffsetError{fileInfo.Size()}
+		}
+	}
+	//Una transferencia nueva inicia con un archivo parcial vacío
+	if incoming.Offset == 0 {
+		file, fileError := os.OpenFile(partialPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
+		if fileError != nil {
+			return nil, fileError
+		}
+		metadataError := writePartialMetadata(partialPath, metadata)
+		if metadataError != nil {
+			file.Close()
+			os.Remove(partialPath)
+			return nil, metadataError
+		}
+		return file, nil
+	}
+	//Al reanudar, los datos guardados deben corresponder a la misma transferencia
+	if metadataError != nil || storedMetadata != metadata {
+		return nil, &ResumeOffsetError{0}
+	}
+	file, fileError := os.OpenFile(partialPath, os.O_RDWR, 0666)
+	if os.IsNotExist(fileError) {
+		return nil, &ResumeOffsetError{0}
+	} else if fileError != nil {
+		return nil, fileError
+	}
+	fileInfo, statError := file.Stat()
+	if statError != nil {
+		file.Close()
+		return nil, statError
+	}
+	if fileInfo.Size() < incoming.Offset {
+		file.Close()
+		return nil, &ResumeOffsetError{fileInfo.Size()}
+	}
+	//Descartar lo que se haya recibido después del byte indicado y calcular el hash de lo anterior
+	truncateError := file.Truncate(incoming.Offset)
+	if truncateError == nil {
+		truncateError = incoming.ResumeFrom(file)
+	}
+	if truncateError != nil {
+		file.Close()
+		return nil, truncateError
+	}
+	return file, nil
+}
+
+//Función que guarda los datos de una transferencia reanudable junto a su archivo parcial
+func writePartialMetadata(partialPath string, metadata partialMetadata) error {
+	content, encodeError := json.Marshal(metadata)
+	if encodeError != nil {
+		return encodeError
+	}
+	return os.WriteFile(partialPath+PARTIAL_METADATA_SUFFIX, content, 0666)
+}
+
+//Función que lee los datos de una transferencia reanudable
+func readPartialMetadata(partialPath string) (partialMetadata, error) {
+	var metadata partialMetadata
+	content, readError := os.ReadFile(partialPath + PARTIAL_METADATA_SUFFIX)
+	if readError != nil {
+		return metadata, readError
+	}
+	return metadata, json.Unmarshal(content, &metadata)
+}
+
+//Función que elimina los datos de una transferencia reanudable (una vez terminada o descartada)
+func removePartialMetadata(partialPath string) {
+	os.Remove(partialPath + PARTIAL_METADATA_SUFFIX)
+}
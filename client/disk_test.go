@@ -0,0 +1,56 @@
+package client
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSuffixedFilename(t *testing.T) {
+	var tests = []struct {
+		filename string
+		suffix   string
+		expected string
+	}{
+		{"informe.pdf", " (1)", "informe (1).pdf"},
+		{"archivo", "_20240101-120000", "archivo_20240101-120000"},
+		{"datos.tar.gz", " (2)", "datos.tar (2).gz"},
+		{strings.Repeat("a", 251) + ".txt", " (1)", strings.Repeat("a", 247) + " (1).txt"},
+		{strings.Repeat("ñ", 125) + ".txt", " (1)", strings.Repeat("ñ", 123) + " (1).txt"},
+		{"a." + strings.Repeat("b", 253), " (1)", "a." + strings.Repeat("b", 249) + " (1)"},
+	}
+	for _, test := range tests {
+		var result string = suffixedFilename(test.filename, test.suffix)
+		if result != test.expected {
+			t.Errorf("suffixedFilename(%q, %q) = %q; expected %q", test.filename, test.suffix, result, test.expected)
+		}
+		if len(result) > LONG_FILENAME_MAX_LENGTH {
+			t.Errorf("suffixedFilename(%q, %q) is %d bytes long", test.filename, test.suffix, len(result))
+		}
+	}
+}
+
+//Prueba que un archivo cuyo nombre tiene el largo máximo pueda recibirse con todas las políticas de conflictos
+func TestFinalizeLongFilename(t *testing.T) {
+	var downloadPath string = t.TempDir() + string(os.PathSeparator)
+	var filename string = strings.Repeat("x", LONG_FILENAME_MAX_LENGTH-4) + ".txt"
+	for _, policy := range []string{CONFLICT_OVERWRITE, CONFLICT_RENAME, CONFLICT_TIMESTAMP, CONFLICT_TIMESTAMP} {
+		partialFile, createError := createPartialFile(downloadPath)
+		if createError != nil {
+			t.Fatalf("createPartialFile failed: %v", createError)
+		}
+		partialFile.Close()
+		savedName, finalizeError := finalizeReceivedFile(partialFile.Name(), downloadPath, filename, policy)
+		if finalizeError != nil {
+			t.Fatalf("finalizeReceivedFile with policy %s failed: %v", policy, finalizeError)
+		}
+		if !fileExists(filepath.Join(downloadPath, savedName)) {
+			t.Errorf("file saved as %q with policy %s doesn't exist", savedName, policy)
+		}
+	}
+	entries, _ := os.ReadDir(downloadPath)
+	if len(entries) != 4 {
+		t.Errorf("download directory has %d files; expected 4", len(entries))
+	}
+}
@@ -0,0 +1,220 @@
+package client
+
+//Archivo con las funciones para cifrar de extremo a extremo el contenido de los archivos enviados a un canal. El
+//contenido se cifra en bloques con AES-256-GCM y una llave derivada de la frase secreta del canal, de modo que el
+//servidor solo retransmite el contenido cifrado (los flags, el nombre y los metadatos del archivo se envían en claro,
+//pero quedan autenticados)
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+//Constantes del cifrado de extremo a extremo
+const ENCRYPTION_SALT_LENGTH = 16        //Longitud de la sal con la que se deriva la llave de cada archivo
+const ENCRYPTION_NONCE_PREFIX_LENGTH = 7 //Longitud del prefijo aleatorio del nonce de cada bloque
+const ENCRYPTION_CHUNK_SIZE = 64 * 1024  //Tamaño de los bloques de contenido que se cifran por separado
+const ENCRYPTION_TAG_LENGTH = 16         //Bytes que añade GCM a cada bloque cifrado
+const KEY_DERIVATION_ITERATIONS = 100000 //Iteraciones de PBKDF2 para derivar la llave
+
+//Longitud de los datos de cifrado que se envían tras el nombre del archivo (sal y prefijo del nonce)
+const ENCRYPTION_BLOCK_LENGTH = ENCRYPTION_SALT_LENGTH + ENCRYPTION_NONCE_PREFIX_LENGTH
+
+//Error que indica que la longitud del contenido cifrado no corresponde a bloques válidos
+var errInvalidCiphertextLength = errors.New("invalid encrypted content length")
+
+//Función que deriva la llave de un archivo a partir de la frase secreta, la sal y el canal (PBKDF2-HMAC-SHA256). Se
+//utiliza el número del canal o, en los canales con nombre, su nombre
+func deriveChannelKey(passphrase []byte, salt []byte, channel Channel) []byte {
+	var saltAndChannel []byte = append([]byte{}, salt...)
+	if channel.IsNamed() {
+		saltAndChannel = append(saltAndChannel, channel.Name...)
+	} else {
+		saltAndChannel = append(saltAndChannel, byte(channel.Number))
+	}
+	return pbkdf2SHA256(passphrase, saltAndChannel, KEY_DERIVATION_ITERATIONS)
+}
+
+//Función que calcula el primer bloque de PBKDF2-HMAC-SHA256 (RFC 8018), es decir, una llave de 32 bytes. Solo se
+//necesita un bloque, pues la llave tiene el mismo tamaño que la salida de SHA-256
+func pbkdf2SHA256(password []byte, salt []byte, iterations int) []byte {
+	var mac = hmac.New(sha256.New, password)
+	mac.Write(salt)
+	mac.Write([]byte{0, 0, 0, 1})
+	var u []byte = mac.Sum(nil)
+	var key []byte = append([]byte{}, u...)
+	for i := 1; i < iterations; i++ {
+		mac.Reset()
+		mac.Write(u)
+		u = mac.Sum(u[:0])
+		for j := range key {
+			key[j] ^= u[j]
+		}
+	}
+	return key
+}
+
+//Función que crea el cifrador AES-256-GCM para una llave
+func newContentCipher(key []byte) (cipher.AEAD, error) {
+	block, cipherError := aes.NewCipher(key)
+	if cipherError != nil {
+		return nil, cipherError
+	}
+	return cipher.NewGCM(block)
+}
+
+//Función que crea el nonce de un bloque: prefijo aleatorio, número de bloque y si es el último (para detectar
+//contenido truncado o reordenado)
+func chunkNonce(prefix []byte, counter uint32, last bool) []byte {
+	var nonce []byte = make([]byte, 12)
+	copy(nonce, prefix)
+	binary.BigEndian.PutUint32(nonce[ENCRYPTION_NONCE_PREFIX_LENGTH:], counter)
+	if last {
+		nonce[11] = 1
+	}
+	return nonce
+}
+
+//Función que calcula el tamaño del contenido cifrado de un archivo
+func encryptedSize(plaintextSize int64) int64 {
+	var chunks int64 = (plaintextSize + ENCRYPTION_CHUNK_SIZE - 1) / ENCRYPTION_CHUNK_SIZE
+	if chunks == 0 {
+		chunks = 1
+	}
+	return plaintextSize + chunks*ENCRYPTION_TAG_LENGTH
+}
+
+//Función que calcula el tamaño original de un archivo a partir del tamaño de su contenido cifrado
+func decryptedSize(ciphertextSize int64) (int64, error) {
+	var chunks int64 = (ciphertextSize + ENCRYPTION_CHUNK_SIZE + ENCRYPTION_TAG_LENGTH - 1) / (ENCRYPTION_CHUNK_SIZE + ENCRYPTION_TAG_LENGTH)
+	if chunks == 0 || ciphertextSize-(chunks-1)*(ENCRYPTION_CHUNK_SIZE+ENCRYPTION_TAG_LENGTH) < ENCRYPTION_TAG_LENGTH {
+		return 0, errInvalidCiphertextLength
+	}
+	return ciphertextSize - chunks*ENCRYPTION_TAG_LENGTH, nil
+}
+
+//Writer que cifra el contenido de un archivo por bloques antes de escribirlo en la conexión
+type encryptingWriter struct {
+	writer  io.Writer
+	aead    cipher.AEAD
+	prefix  []byte
+	aad     []byte //Datos autenticados pero no cifrados (los flags, el nombre y los metadatos del archivo)
+	counter uint32
+	buffer  []byte
+}
+
+//Función que crea el writer de cifrado de un archivo. Retorna también los datos de cifrado (sal y prefijo del nonce) que
+//se envían tras el nombre del archivo
+func newEncryptingWriter(writer io.Writer, passphrase []byte, channel Channel, authenticatedData []byte) (*encryptingWriter, []byte, error) {
+	var block []byte = make([]byte, ENCRYPTION_BLOCK_LENGTH)
+	_, randomError := rand.Read(block)
+	if randomError != nil {
+		return nil, nil, randomError
+	}
+	aead, cipherError := newContentCipher(deriveChannelKey(passphrase, block[:ENCRYPTION_SALT_LENGTH], channel))
+	if cipherError != nil {
+		return nil, nil, cipherError
+	}
+	return &encryptingWriter{
+		writer: writer,
+		aead:   aead,
+		prefix: block[ENCRYPTION_SALT_LENGTH:],
+		aad:    authenticatedData,
+		buffer: make([]byte, 0, ENCRYPTION_CHUNK_SIZE+ENCRYPTION_TAG_LENGTH),
+	}, block, nil
+}
+
+func (w *encryptingWriter) Write(p []byte) (int, error) {
+	var written int = 0
+	for len(p) > 0 {
+		//Un bloque lleno solo se cifra al llegar más contenido, pues el último bloque se marca como tal
+		if len(w.buffer) == ENCRYPTION_CHUNK_SIZE {
+			sealError := w.sealChunk(false)
+			if sealError != nil {
+				return written, sealError
+			}
+		}
+		var n int = copy(w.buffer[len(w.buffer):ENCRYPTION_CHUNK_SIZE], p)
+		w.buffer = w.buffer[:len(w.buffer)+n]
+		p = p[n:]
+		written += n
+	}
+	return written, nil
+}
+
+//Función que cifra y envía el último bloque del contenido
+func (w *encryptingWriter) Close() error {
+	return w.sealChunk(true)
+}
+
+func (w *encryptingWriter) sealChunk(last bool) error {
+	var sealed []byte = w.aead.Seal(w.buffer[:0], chunkNonce(w.prefix, w.counter, last), w.buffer, w.aad)
+	w.counter++
+	_, writeError := w.writer.Write(sealed)
+	w.buffer = w.buffer[:0]
+	return writeError
+}
+
+//Reader que descifra por bloques el contenido cifrado de un archivo leído de la conexión
+type decryptingReader struct {
+	reader    io.Reader
+	aead      cipher.AEAD
+	prefix    []byte
+	aad       []byte
+	counter   uint32
+	remaining int64 //Bytes cifrados que faltan leer
+	chunk     []byte
+	plaintext []byte //Contenido descifrado que aún no se ha entregado
+}
+
+//Función que crea el reader de descifrado a partir de los datos de cifrado recibidos tras el nombre del archivo
+func newDecryptingReader(reader io.Reader, passphrase []byte, channel Channel, authenticatedData []byte, block []byte, ciphertextSize int64) (*decryptingReader, error) {
+	aead, cipherError := newContentCipher(deriveChannelKey(passphrase, block[:ENCRYPTION_SALT_LENGTH], channel))
+	if cipherError != nil {
+		return nil, cipherError
+	}
+	return &decryptingReader{
+		reader:    reader,
+		aead:      aead,
+		prefix:    block[ENCRYPTION_SALT_LENGTH:],
+		aad:       authenticatedData,
+		remaining: ciphertextSize,
+		chunk:     make([]byte, ENCRYPTION_CHUNK_SIZE+ENCRYPTION_TAG_LENGTH),
+	}, nil
+}
+
+func (r *decryptingReader) Read(p []byte) (int, error) {
+	for len(r.plaintext) == 0 {
+		if r.remaining == 0 {
+			return 0, io.EOF
+		}
+		//Todos los bloques tienen el tamaño máximo excepto el último
+		var chunkLength int64 = ENCRYPTION_CHUNK_SIZE + ENCRYPTION_TAG_LENGTH
+		var last bool = r.remaining <= chunkLength
+		if last {
+			chunkLength = r.remaining
+		}
+		_, readError := io.ReadFull(r.reader, r.chunk[:chunkLength])
+		if readError == io.ErrUnexpectedEOF {
+			return 0, io.EOF
+		} else if readError != nil {
+			return 0, readError
+		}
+		plaintext, openError := r.aead.Open(r.chunk[:0], chunkNonce(r.prefix, r.counter, last), r.chunk[:chunkLength], r.aad)
+		if openError != nil {
+			return 0, ErrDecryptionFailed
+		}
+		r.counter++
+		r.remaining -= chunkLength
+		r.plaintext = plaintext
+	}
+	var n int = copy(p, r.plaintext)
+	r.plaintext = r.plaintext[n:]
+	return n, nil
+}
@@ -0,0 +1,46 @@
+package client
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+//Vectores de prueba conocidos de PBKDF2-HMAC-SHA256 (el último es el de RFC 7914, sección 11, acortado a 32 bytes)
+func TestPBKDF2SHA256(t *testing.T) {
+	var tests = []struct {
+		password   string
+		salt       string
+		iterations int
+		expected   string
+	}{
+		{"password", "salt", 1, "120fb6cffcf8b32c43e7225256c4f837a86548c92ccc35480805987cb70be17b"},
+		{"password", "salt", 2, "ae4d0c95af6b46d32d0adff928f06dd02a303f8ef3c251dfd6e2d85a95474c43"},
+		{"password", "salt", 4096, "c5e478d59288c841aa530db6845c4c8d962893a001ce4e11a4963873aa98134a"},
+		{"passwordPASSWORDpassword", "saltSALTsaltSALTsaltSALTsaltSALTsalt", 4096, "348c89dbcbd32b2f32d814b8116e84cf2b17347ebc1800181c4e2a1fb8dd53e1"},
+		{"passwd", "salt", 1, "55ac046e56e3089fec1691c22544b605f94185216dde0465e68b9d57c20dacbc"},
+	}
+	for _, test := range tests {
+		var key string = hex.EncodeToString(pbkdf2SHA256([]byte(test.password), []byte(test.salt), test.iterations))
+		if key != test.expected {
+			t.Errorf("pbkdf2SHA256(%q, %q, %d) = %s; expected %s", test.password, test.salt, test.iterations, key, test.expected)
+		}
+	}
+}
+
+//La llave de cada canal se deriva de la sal seguida del número o del nombre del canal
+func TestDeriveChannelKey(t *testing.T) {
+	var salt []byte = []byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+	var tests = []struct {
+		channel  Channel
+		expected string
+	}{
+		{NumberedChannel(1), "05978fd0b7620d7e785a32550846d1eb12c64b69e7d2d595b2624f8318ef3191"},
+		{NamedChannel("builds"), "f8b11ad4047c0482dd70de84df0c4275413d0e9310f7223aa64a5f3e311f7a03"},
+	}
+	for _, test := range tests {
+		var key string = hex.EncodeToString(deriveChannelKey([]byte("frase"), salt, test.channel))
+		if key != test.expected {
+			t.Errorf("deriveChannelKey for channel %v = %s; expected %s", test.channel, key, test.expected)
+		}
+	}
+}
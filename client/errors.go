@@ -0,0 +1,80 @@
+package client
+
+//Archivo con los errores que retorna el cliente
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+//Códigos de estado de los errores (coinciden con los códigos de salida del cliente de línea de comandos)
+const STATUS_USAGE = 1         //Parámetros inválidos, o una operación que el servidor no soporta
+const STATUS_COMMUNICATION = 2 //Error de comunicación con el servidor, o el servidor rechazó la solicitud
+const STATUS_PROTOCOL = 3      //Mensaje inválido o error interno
+const STATUS_INTEGRITY = 4     //El contenido recibido no es íntegro (hash distinto o no se pudo descifrar)
+const STATUS_FILESYSTEM = 5    //Error del sistema de archivos
+const STATUS_TIMEOUT = 6       //Se superó un tiempo máximo de espera (ver timeouts.go)
+
+//Error de una operación del cliente
+type Error struct {
+	Status  int    //Código de estado
+	Reason  string //Motivo breve que se envía al servidor al rechazar una transferencia recibida
+	Message string //Descripción del error
+	Err     error  //Error que lo originó (nil si no hay)
+}
+
+func (e *Error) Error() string {
+	if e.Err == nil {
+		return e.Message
+	}
+	if e.Message == "" {
+		return e.Err.Error()
+	}
+	return e.Message + ": " + e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+//Función que crea un error del cliente. Los errores causados por superar un tiempo máximo de espera tienen siempre el
+//código STATUS_TIMEOUT
+func newError(status int, reason string, message string, err error) *Error {
+	var timeoutError *TimeoutError
+	if errors.As(err, &timeoutError) {
+		status = STATUS_TIMEOUT
+	}
+	return &Error{Status: status, Reason: reason, Message: message, Err: err}
+}
+
+//Error que indica que el servidor rechazó una solicitud (respondió con el comando error)
+type ServerError struct {
+	Message string //Contenido de la respuesta del servidor
+}
+
+func (e *ServerError) Error() string {
+	return "Server error (" + e.Message + ")"
+}
+
+//Error que indica que al terminar una suscripción se interrumpieron transferencias que no alcanzaron a completarse
+//dentro del periodo de gracia (ver Client.GracePeriod)
+type AbortedTransfersError struct {
+	Files []string //Nombres de los archivos cuya transferencia se interrumpió
+	Err   error    //Motivo por el que terminó la suscripción (por ejemplo, ctx.Err()), nil si se canceló con Unsubscribe
+}
+
+func (e *AbortedTransfersError) Error() string {
+	return strconv.Itoa(len(e.Files)) + " transfer(s) aborted during shutdown: " + strings.Join(e.Files, ", ")
+}
+
+func (e *AbortedTransfersError) Unwrap() error {
+	return e.Err
+}
+
+//Errores conocidos (pueden compararse con errors.Is)
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+var ErrDecryptionFailed = errors.New("decryption failed (wrong passphrase or corrupted content)")
+var ErrFileExists = errors.New("file already exists")
+var ErrNotSubscribed = errors.New("not subscribed to channel")
+var ErrAlreadySubscribed = errors.New("already subscribed to channel")
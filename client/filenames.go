@@ -0,0 +1,146 @@
+package client
+
+//Archivo con funciones para validar y sanear los nombres de archivo recibidos a través del servidor
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+//Errores posibles al validar el nombre de un archivo recibido
+var errEmptyFilename = errors.New("empty filename")
+var errFilenamePadding = errors.New("filename field contains data after its padding")
+var errInvalidEncoding = errors.New("filename is not valid UTF-8")
+var errControlCharacter = errors.New("filename contains control or format characters")
+var errPathSeparator = errors.New("filename contains path separators")
+var errRelativeName = errors.New("filename refers to a directory")
+var errInvalidPath = errors.New("path contains empty, '.' or '..' components")
+var errDriveName = errors.New("filename contains a drive or stream separator")
+var errFilenameTooLong = errors.New("filename is too long")
+var errUnsupportedFlags = errors.New("message specifies unsupported flags")
+
+//Nombres reservados por Windows para dispositivos (no pueden usarse como nombre de archivo aunque tengan extensión)
+var windowsReservedNames = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"CONIN$": true, "CONOUT$": true,
+	"COM0": true, "COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true, "COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT0": true, "LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true, "LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+//Caracteres que Windows no admite en nombres de archivo y que se reemplazan por '_'
+const windowsInvalidCharacters = "<>\"|?*"
+
+//Función que obtiene el nombre de archivo a partir del campo de longitud fija de un mensaje
+func parseFilenameField(field []byte) (string, error) {
+	//Los bytes no utilizados se llenan con el caracter \x00; después del primero no puede haber otra cosa
+	var nameLength int = bytes.IndexByte(field, 0)
+	if nameLength == -1 {
+		nameLength = len(field)
+	}
+	for _, b := range field[nameLength:] {
+		if b != 0 {
+			return "", errFilenamePadding
+		}
+	}
+	return sanitizeFilename(string(field[:nameLength]))
+}
+
+//Función que lee el campo con el nombre del archivo en el formato de envío extendido (flags, longitud y nombre)
+//Retorna los flags del mensaje y el nombre (aún sin validar)
+func readExtendedFilenameField(reader io.Reader) (byte, []byte, error) {
+	var fieldHeader []byte = make([]byte, EXTENDED_FILENAME_HEADER_LENGTH)
+	_, readError := io.ReadFull(reader, fieldHeader)
+	if readError != nil {
+		return 0, nil, readError
+	}
+	//Se rechazan los flags que el cliente no conoce, pues cambian el formato del resto del mensaje
+	var flags byte = fieldHeader[0]
+	if flags&^SUPPORTED_SEND_FLAGS != 0 {
+		return 0, nil, errUnsupportedFlags
+	}
+	var nameLength int = int(binary.LittleEndian.Uint16(fieldHeader[1:]))
+	if nameLength > LONG_FILENAME_MAX_LENGTH {
+		return 0, nil, errFilenameTooLong
+	}
+	var nameBuffer []byte = make([]byte, nameLength)
+	_, readError = io.ReadFull(reader, nameBuffer)
+	if readError != nil {
+		return 0, nil, readError
+	}
+	return flags, nameBuffer, nil
+}
+
+//Función que valida un nombre de archivo recibido y lo reescribe si es necesario para que sea seguro crearlo dentro
+//del directorio de descarga. Los nombres que podrían escapar del directorio se rechazan
+func sanitizeFilename(filename string) (string, error) {
+	if len(filename) == 0 {
+		return "", errEmptyFilename
+	}
+	if !utf8.ValidString(filename) {
+		return "", errInvalidEncoding
+	}
+	//Rechazar caracteres de control (incluyendo \x00), caracteres de formato (como U+202E, que invierte el sentido del
+	//texto y permite disfrazar la extensión) y separadores de directorio de cualquier sistema
+	for _, r := range filename {
+		if unicode.IsControl(r) || unicode.Is(unicode.Cf, r) {
+			return "", errControlCharacter
+		}
+		if r == '/' || r == '\\' {
+			return "", errPathSeparator
+		}
+		if r == ':' {
+			return "", errDriveName
+		}
+	}
+	if filename == "." || filename == ".." {
+		return "", errRelativeName
+	}
+	//Reemplazar caracteres que Windows no admite
+	filename = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(windowsInvalidCharacters, r) {
+			return '_'
+		}
+		return r
+	}, filename)
+	//Windows ignora los puntos y espacios finales, por lo que se eliminan
+	filename = strings.TrimRight(filename, ". ")
+	if len(filename) == 0 {
+		return "", errRelativeName
+	}
+	//Los nombres reservados de dispositivos se evitan anteponiendo '_' (se comparan sin extensión)
+	var baseName string = strings.ToUpper(strings.TrimRight(strings.SplitN(filename, ".", 2)[0], " "))
+	if windowsReservedNames[baseName] {
+		filename = "_" + filename
+		//El prefijo puede dejar el nombre más largo que el máximo, por lo que se acorta conservando la extensión
+		if len(filename) > LONG_FILENAME_MAX_LENGTH {
+			filename = strings.TrimRight(suffixedFilename(filename, ""), ". ")
+		}
+	}
+	return filename, nil
+}
+
+//Función que valida la ruta relativa de un archivo de un directorio (con '/' como separador) y sanea cada uno de sus
+//componentes como un nombre de archivo. Se rechazan las rutas absolutas y las que contienen componentes vacíos, '.' o
+//'..', de modo que la ruta siempre quede dentro del directorio de descarga
+func sanitizeRelativePath(path string) (string, error) {
+	if len(path) == 0 {
+		return "", errEmptyFilename
+	}
+	var components []string = strings.Split(path, "/")
+	for i, component := range components {
+		if component == "" || component == "." || component == ".." {
+			return "", errInvalidPath
+		}
+		sanitized, sanitizeError := sanitizeFilename(component)
+		if sanitizeError != nil {
+			return "", sanitizeError
+		}
+		components[i] = sanitized
+	}
+	return strings.Join(components, "/"), nil
+}
@@ -0,0 +1,125 @@
+package client
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSanitizeFilename(t *testing.T) {
+	var tests = []struct {
+		name     string
+		expected string //Nombre saneado esperado (vacío si se rechaza)
+		err      error
+	}{
+		{"informe.pdf", "informe.pdf", nil},
+		{"archivo con espacios.txt", "archivo con espacios.txt", nil},
+		{"ñandú.txt", "ñandú.txt", nil},
+		{"", "", errEmptyFilename},
+		{".", "", errRelativeName},
+		{"..", "", errRelativeName},
+		{"...", "", errRelativeName},
+		{"../../.bashrc", "", errPathSeparator},
+		{"/etc/passwd", "", errPathSeparator},
+		{"..\\..\\Windows\\win.ini", "", errPathSeparator},
+		{"\\\\server\\share", "", errPathSeparator},
+		{"C:", "", errDriveName},
+		{"C:autoexec.bat", "", errDriveName},
+		{"archivo.txt:stream", "", errDriveName},
+		{"nul\x00.txt", "", errControlCharacter},
+		{"linea\nnueva", "", errControlCharacter},
+		{"tab\t.txt", "", errControlCharacter},
+		{"\x7f.txt", "", errControlCharacter},
+		{"factura\u202Efdp.exe", "", errControlCharacter},
+		{"cero\u200Bancho.txt", "", errControlCharacter},
+		{"\xff\xfe.txt", "", errInvalidEncoding},
+		{"a<b>c|d?e*f\".txt", "a_b_c_d_e_f_.txt", nil},
+		{"final. . .", "final", nil},
+		{"CON", "_CON", nil},
+		{"con.txt", "_con.txt", nil},
+		{"Aux.tar.gz", "_Aux.tar.gz", nil},
+		{"NUL .txt", "_NUL .txt", nil},
+		{"COM0", "_COM0", nil},
+		{"com9.log", "_com9.log", nil},
+		{"LPT0.txt", "_LPT0.txt", nil},
+		{"CONIN$", "_CONIN$", nil},
+		{"conout$.txt", "_conout$.txt", nil},
+		{"CONSOLE.txt", "CONSOLE.txt", nil},
+		{"COM10", "COM10", nil},
+		{"CON." + strings.Repeat("a", 251), "_CON." + strings.Repeat("a", 250), nil},
+		{"nul" + strings.Repeat(" ", 248) + ".txt", "_nul" + strings.Repeat(" ", 247) + ".txt", nil},
+		{strings.Repeat("a", LONG_FILENAME_MAX_LENGTH), strings.Repeat("a", LONG_FILENAME_MAX_LENGTH), nil},
+	}
+	for _, test := range tests {
+		sanitized, err := sanitizeFilename(test.name)
+		if err != test.err || sanitized != test.expected {
+			t.Errorf("sanitizeFilename(%q) = %q, %v; expected %q, %v", test.name, sanitized, err, test.expected, test.err)
+		}
+		if len(sanitized) > LONG_FILENAME_MAX_LENGTH {
+			t.Errorf("sanitizeFilename(%q) is %d bytes long", test.name, len(sanitized))
+		}
+	}
+}
+
+func TestParseFilenameField(t *testing.T) {
+	//Función que crea un campo de longitud fija con el contenido indicado
+	var field = func(content string) []byte {
+		var buffer []byte = make([]byte, FILENAME_MAX_LENGTH)
+		copy(buffer, content)
+		return buffer
+	}
+	var tests = []struct {
+		description string
+		field       []byte
+		expected    string
+		err         error
+	}{
+		{"name with padding", field("informe.pdf"), "informe.pdf", nil},
+		{"name filling the field", []byte(strings.Repeat("a", FILENAME_MAX_LENGTH)), strings.Repeat("a", FILENAME_MAX_LENGTH), nil},
+		{"empty field", field(""), "", errEmptyFilename},
+		{"data after padding", field("seguro.txt\x00../../.bashrc"), "", errFilenamePadding},
+		{"data at end of padding", field("a.txt" + strings.Repeat("\x00", FILENAME_MAX_LENGTH-6) + "x"), "", errFilenamePadding},
+		{"padding before name", field("\x00oculto.txt"), "", errFilenamePadding},
+		{"traversal", field("../../.bashrc"), "", errPathSeparator},
+		{"absolute path", field("/etc/passwd"), "", errPathSeparator},
+		{"backslash", field("..\\win.ini"), "", errPathSeparator},
+		{"drive", field("C:boot.ini"), "", errDriveName},
+		{"control character", field("a\rb.txt"), "", errControlCharacter},
+		{"reserved name", field("PRN.txt"), "_PRN.txt", nil},
+	}
+	for _, test := range tests {
+		parsed, err := parseFilenameField(test.field)
+		if err != test.err || parsed != test.expected {
+			t.Errorf("%s: parseFilenameField(%q) = %q, %v; expected %q, %v", test.description, test.field, parsed, err, test.expected, test.err)
+		}
+	}
+}
+
+func TestSanitizeRelativePath(t *testing.T) {
+	var tests = []struct {
+		path     string
+		expected string
+		err      error
+	}{
+		{"build/bin/app", "build/bin/app", nil},
+		{"build/a?b.txt", "build/a_b.txt", nil},
+		{"build/CON/aux.txt", "build/_CON/_aux.txt", nil},
+		{"", "", errEmptyFilename},
+		{"../../.bashrc", "", errInvalidPath},
+		{"build/../../.bashrc", "", errInvalidPath},
+		{"build/./a.txt", "", errInvalidPath},
+		{"/etc/passwd", "", errInvalidPath},
+		{"build//a.txt", "", errInvalidPath},
+		{"build/", "", errInvalidPath},
+		{"build\\..\\a.txt", "", errPathSeparator},
+		{"C:/Windows/win.ini", "", errDriveName},
+		{"build/\x00a.txt", "", errControlCharacter},
+		{"build/\u202Etxt.exe", "", errControlCharacter},
+		{"build/. .", "", errRelativeName},
+	}
+	for _, test := range tests {
+		sanitized, err := sanitizeRelativePath(test.path)
+		if err != test.err || sanitized != test.expected {
+			t.Errorf("sanitizeRelativePath(%q) = %q, %v; expected %q, %v", test.path, sanitized, err, test.expected, test.err)
+		}
+	}
+}
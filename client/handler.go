@@ -0,0 +1,145 @@
+package client
+
+//Archivo con la interfaz de los handlers que procesan los archivos recibidos en un canal suscrito
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"hash"
+	"io"
+)
+
+//Handler que procesa los archivos recibidos en un canal suscrito. ReceiveFile se llama en una goroutine distinta por
+//cada archivo; si retorna nil, se informa al servidor que el archivo se recibió correctamente, y si retorna un error,
+//que se rechazó (un *ResumeOffsetError pide al servidor continuar la transferencia desde otro byte). Si se cancela el
+//contexto, la conexión se cierra (por lo que Read falla) y el handler debe descartar lo recibido y retornar ctx.Err()
+type Handler interface {
+	ReceiveFile(ctx context.Context, file *IncomingFile) error
+}
+
+//Adaptador para utilizar una función como Handler
+type HandlerFunc func(ctx context.Context, file *IncomingFile) error
+
+func (f HandlerFunc) ReceiveFile(ctx context.Context, file *IncomingFile) error {
+	return f(ctx, file)
+}
+
+//Archivo que se está recibiendo. Su contenido (ya descifrado, si se envió cifrado) se lee con Read; al llegar al final
+//se verifica el hash enviado por el remitente, y si no coincide Read retorna un *Error con ErrChecksumMismatch en vez
+//de io.EOF. Por lo tanto, el handler debe leer todo el contenido antes de dar por recibido el archivo
+type IncomingFile struct {
+	Name       string        //Nombre del archivo (validado y saneado; en los archivos de un directorio, ruta relativa con '/' como separador)
+	Channel    Channel       //Canal por el que se recibió el archivo (tal como se suscribió)
+	Size       int64         //Tamaño total del archivo (-1 en los archivos enviados por bloques hasta terminar de leerlos)
+	Offset     int64         //Byte del archivo a partir del cual se recibe el contenido (mayor a 0 al reanudar una transferencia)
+	TransferID []byte        //Identificador de la transferencia si es reanudable (nil si no lo es)
+	Encrypted  bool          //Indica si el contenido se envió cifrado de extremo a extremo
+	Manifest   bool          //Indica si el contenido es el manifiesto del directorio Name (ver ReadManifest)
+	Metadata   *FileMetadata //Metadatos enviados por el remitente (nil si no se enviaron)
+
+	reader        io.Reader //Contenido del archivo (descifrado si corresponde)
+	connection    io.Reader //Conexión de la que se lee el mensaje (para leer el hash al final del contenido)
+	decrypter     *decryptingReader
+	chunks        *chunkedReader //Contenido de un archivo enviado por bloques (nil si se envió con su tamaño)
+	received      int64          //Bytes leídos de un archivo enviado por bloques
+	hash          hash.Hash
+	trailerLength int64 //Longitud del hash al final del contenido (0 si no se envió)
+	remaining     int64 //Bytes del contenido que faltan leer
+	err           error //Resultado de la lectura una vez terminada (io.EOF o el error correspondiente)
+}
+
+func (f *IncomingFile) Read(p []byte) (int, error) {
+	if f.err != nil {
+		return 0, f.err
+	}
+	if f.chunks != nil {
+		return f.readChunked(p)
+	}
+	//El contenido cifrado se lee hasta el último bloque, aunque este no tenga contenido, para autenticarlo
+	if f.remaining == 0 && (f.decrypter == nil || f.decrypter.remaining == 0) {
+		f.err = f.verify()
+		return 0, f.err
+	}
+	//Leer sin exceder lo que falta del archivo
+	if int64(len(p)) > f.remaining {
+		p = p[:f.remaining]
+	}
+	n, readError := f.reader.Read(p)
+	f.hash.Write(p[:n])
+	f.remaining -= int64(n)
+	//Error check
+	if readError == io.EOF && f.remaining > 0 {
+		f.err = newError(STATUS_COMMUNICATION, "file incomplete read", fmt.Sprintf("Could not read file content completely (expected: %d, real: %d)", f.Size-f.Offset, f.Size-f.Offset-f.remaining), nil)
+	} else if readError == ErrDecryptionFailed { //El contenido cifrado no es auténtico
+		f.err = newError(STATUS_INTEGRITY, "decryption failed", "Could not decrypt file "+f.Name, ErrDecryptionFailed)
+	} else if readError != nil && readError != io.EOF { //Hubo un error de otro tipo
+		f.err = newError(STATUS_COMMUNICATION, "file read error", "Error while reading file content", readError)
+	}
+	//Los datos leídos se entregan antes que el error
+	if n > 0 {
+		return n, nil
+	}
+	return 0, f.err
+}
+
+//Función que lee el contenido de un archivo enviado por bloques, cuyo tamaño se conoce al terminar
+func (f *IncomingFile) readChunked(p []byte) (int, error) {
+	n, readError := f.chunks.Read(p)
+	f.hash.Write(p[:n])
+	f.received += int64(n)
+	if readError == io.EOF {
+		f.Size = f.received
+		f.err = f.verify()
+		if f.err == io.EOF && f.Metadata != nil && f.Metadata.Size != f.Size {
+			f.err = newError(STATUS_INTEGRITY, "size mismatch", fmt.Sprintf("Could not verify file %v (expected size: %d, real: %d)", f.Name, f.Metadata.Size, f.Size), nil)
+		}
+	} else if readError != nil {
+		f.err = newError(STATUS_COMMUNICATION, "file read error", "Error while reading file content", readError)
+	}
+	if n > 0 {
+		return n, nil
+	}
+	return 0, f.err
+}
+
+//Función que verifica el hash enviado al final del contenido. Retorna io.EOF si el contenido es íntegro
+func (f *IncomingFile) verify() error {
+	if f.trailerLength == 0 {
+		return io.EOF
+	}
+	//En los archivos enviados por bloques, el hash llega en el mensaje que indica el final
+	var trailerBuffer []byte
+	if f.chunks != nil {
+		trailerBuffer = f.chunks.trailer
+	} else {
+		trailerBuffer = make([]byte, f.trailerLength)
+		_, trailerError := io.ReadFull(f.connection, trailerBuffer)
+		//Error check
+		if trailerError != nil {
+			return newError(STATUS_COMMUNICATION, "file read error", "Error while reading file checksum", trailerError)
+		}
+	}
+	if !bytes.Equal(trailerBuffer, f.hash.Sum(nil)) {
+		return newError(STATUS_INTEGRITY, "checksum mismatch", "Could not verify file "+f.Name, ErrChecksumMismatch)
+	}
+	return io.EOF
+}
+
+//Función que añade al hash del archivo los bytes previos al byte inicial de una transferencia reanudada (los que el
+//handler ya tenía de una entrega anterior), leyéndolos de prefix. Debe llamarse antes de leer el contenido
+func (f *IncomingFile) ResumeFrom(prefix io.Reader) error {
+	_, copyError := io.CopyN(f.hash, prefix, f.Offset)
+	return copyError
+}
+
+//Función que lee lo que falte del contenido (verificándolo) una vez que el handler terminó
+func (f *IncomingFile) finish() error {
+	if f.err == nil {
+		io.Copy(io.Discard, f)
+	}
+	if f.err == io.EOF {
+		return nil
+	}
+	return f.err
+}
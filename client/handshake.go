@@ -0,0 +1,137 @@
+package client
+
+//Archivo con las funciones para negociar la versión del protocolo y las capacidades soportadas con el servidor
+
+import (
+	"Client/protocol"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"sync/atomic"
+	"time"
+)
+
+//Constantes del saludo (hello) con el que se negocia el protocolo
+const PROTOCOL_VERSION = 2                //Versión del protocolo implementada (la versión 1 es la original, sin saludo)
+const HELLO_CONTENT_LENGTH = 5            //Longitud del contenido del saludo: versión (1 byte) + capacidades (4 bytes)
+const HANDSHAKE_TIMEOUT = 5 * time.Second //Tiempo máximo de espera de la respuesta al saludo
+const MAX_HELLO_CONTENT_LENGTH = 1024     //Longitud máxima aceptada para el contenido de un saludo recibido
+
+//Capacidades opcionales del protocolo (bits del campo de capacidades del saludo)
+const CAP_LONG_FILENAMES uint32 = 1 << 0 //Formato de envío extendido, con nombres de longitud variable
+const CAP_CHECKSUM uint32 = 1 << 1       //Hash SHA-256 del archivo al final del contenido (requiere el formato extendido)
+const CAP_RESUME uint32 = 1 << 2         //Reanudación de transferencias interrumpidas (requiere el formato extendido)
+const CAP_ENCRYPTION uint32 = 1 << 3     //Contenido cifrado de extremo a extremo (requiere el formato extendido)
+const CAP_SERVER_PUSH uint32 = 1 << 4    //Entrega de archivos a través de la conexión de suscripción (ver push.go)
+const CAP_NAMED_CHANNELS uint32 = 1 << 5 //Canales identificados por su nombre (ver channels.go)
+const CAP_CHUNKED uint32 = 1 << 6        //Envío por bloques de contenido de tamaño desconocido (ver chunked.go)
+const CAP_METADATA uint32 = 1 << 7       //Metadatos del archivo tras su nombre (ver metadata.go)
+
+//Capacidades soportadas por este cliente
+const CLIENT_CAPABILITIES uint32 = CAP_LONG_FILENAMES | CAP_CHECKSUM | CAP_RESUME | CAP_ENCRYPTION | CAP_SERVER_PUSH | CAP_NAMED_CHANNELS | CAP_CHUNKED | CAP_METADATA
+
+//Error que indica que el servidor no respondió al saludo con otro saludo
+var errLegacyPeer = errors.New("peer does not support protocol negotiation")
+
+//Función que crea el mensaje de saludo con la versión y capacidades del cliente
+func createHelloMessage() protocol.Message {
+	var body []byte = make([]byte, HELLO_CONTENT_LENGTH)
+	body[0] = PROTOCOL_VERSION
+	binary.LittleEndian.PutUint32(body[1:], CLIENT_CAPABILITIES)
+	return protocol.Message{Command: protocol.HELLO_COMMAND, Content: body}
+}
+
+//Función que parsea el contenido de un saludo recibido y retorna las capacidades en común con el cliente
+func parseHelloContent(content []byte) (uint32, error) {
+	if len(content) < HELLO_CONTENT_LENGTH {
+		return 0, errors.New("hello message is too short")
+	}
+	//Versiones futuras pueden añadir campos al final del saludo, por lo que estos se ignoran
+	var peerCapabilities uint32 = binary.LittleEndian.Uint32(content[1:5])
+	return peerCapabilities & CLIENT_CAPABILITIES, nil
+}
+
+//Función que lee el contenido de un saludo ya identificado por su header
+func readHelloContent(decoder *protocol.Decoder, header protocol.Header) (uint32, error) {
+	message, readError := decoder.DecodeContent(header, MAX_HELLO_CONTENT_LENGTH)
+	if readError == protocol.ErrContentTooLong {
+		return 0, errors.New("hello message is too long")
+	} else if readError != nil {
+		return 0, readError
+	}
+	return parseHelloContent(message.Content)
+}
+
+//Función que realiza el saludo en una conexión iniciada por el cliente: envía su versión y capacidades y espera las del
+//servidor. Si el servidor no responde con un saludo, se trata de un servidor que solo soporta el protocolo original
+func sendHello(connection net.Conn) (uint32, error) {
+	writeError := protocol.NewEncoder(connection).Encode(createHelloMessage())
+	if writeError != nil {
+		return 0, writeError
+	}
+	//No se espera indefinidamente por la respuesta, pues un servidor antiguo podría no responder
+	connection.SetReadDeadline(time.Now().Add(HANDSHAKE_TIMEOUT))
+	defer connection.SetReadDeadline(time.Time{})
+	var decoder *protocol.Decoder = protocol.NewDecoder(connection)
+	header, readError := decoder.DecodeHeader()
+	if readError != nil {
+		return 0, errLegacyPeer
+	}
+	if header.Command != protocol.HELLO_COMMAND {
+		return 0, errLegacyPeer
+	}
+	return readHelloContent(decoder, header)
+}
+
+//Función que responde al saludo recibido (cuyo header ya se leyó) en una conexión iniciada por el servidor
+func answerHello(connection io.ReadWriter, header protocol.Header) (uint32, error) {
+	capabilities, helloError := readHelloContent(protocol.NewDecoder(connection), header)
+	if helloError != nil {
+		return 0, helloError
+	}
+	writeError := protocol.NewEncoder(connection).Encode(createHelloMessage())
+	if writeError != nil {
+		return 0, writeError
+	}
+	return capabilities, nil
+}
+
+//Función que establece una conexión con el servidor y negocia las capacidades a utilizar. Si el servidor no soporta el
+//saludo, se vuelve a conectar para utilizar el protocolo original (sin capacidades opcionales). Una vez detectado un
+//servidor antiguo, no se vuelve a intentar el saludo en las siguientes conexiones. Si transfer es true, a la conexión
+//se le aplica el tiempo máximo de la transferencia completa
+func (c *Client) connectToServer(ctx context.Context, transfer bool) (net.Conn, uint32, error) {
+	connection, connectionError := c.dial(ctx, c.serverAddress(), transfer)
+	if connectionError != nil {
+		return nil, 0, connectionError
+	}
+	if atomic.LoadInt32(&c.legacyServer) == 1 {
+		return connection, 0, nil
+	}
+	var stopWatching func() = closeOnCancel(ctx, connection)
+	capabilities, helloError := sendHello(connection)
+	stopWatching()
+	//Si se canceló el contexto durante el saludo, la conexión ya se cerró
+	if ctx.Err() != nil {
+		connection.Close()
+		return nil, 0, ctx.Err()
+	}
+	if helloError == nil {
+		return connection, capabilities, nil
+	}
+	//El servidor no soporta el saludo (o este falló): se descarta la conexión y se usa el protocolo original
+	connection.Close()
+	if helloError == errLegacyPeer {
+		c.logln("Server does not support protocol negotiation, falling back to legacy protocol")
+		atomic.StoreInt32(&c.legacyServer, 1)
+	} else {
+		c.logln("WARNING: Protocol negotiation failed (" + helloError.Error() + "), falling back to legacy protocol")
+	}
+	connection, connectionError = c.dial(ctx, c.serverAddress(), transfer)
+	if connectionError != nil {
+		return nil, 0, connectionError
+	}
+	return connection, 0, nil
+}
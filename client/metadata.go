@@ -0,0 +1,127 @@
+package client
+
+//Archivo con los metadatos de los archivos enviados (fecha de modificación, permisos, tamaño y tipo de contenido). Si
+//el servidor lo soporta, se envían en un bloque tras el nombre del archivo (FLAG_METADATA): la longitud del bloque
+//(2 bytes), la fecha de modificación (8 bytes, nanosegundos desde 1970), los permisos (4 bytes), el tamaño (8 bytes) y
+//el tipo de contenido prefijado por su longitud (1 byte). Todos los números van en little endian, y los campos que se
+//añadan en el futuro al final del bloque se ignoran
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"time"
+)
+
+//Constantes del bloque de metadatos
+const METADATA_LENGTH_FIELD = 2     //Bytes con la longitud del bloque
+const METADATA_FIXED_LENGTH = 21    //Bytes de los campos de longitud fija (incluyendo la longitud del tipo de contenido)
+const MAX_METADATA_LENGTH = 1024    //Longitud máxima aceptada para un bloque recibido
+const CONTENT_SNIFF_LENGTH = 512    //Bytes del archivo con los que se detecta el tipo de contenido
+const MAX_CONTENT_TYPE_LENGTH = 255 //Longitud máxima del tipo de contenido
+
+//Error que indica que el bloque de metadatos recibido no es válido
+var errInvalidMetadata = errors.New("invalid metadata block")
+
+//Metadatos de un archivo
+type FileMetadata struct {
+	ModTime     time.Time   //Fecha de modificación
+	Mode        os.FileMode //Permisos (solo los bits rwx; el receptor solo aplica los de ejecución)
+	Size        int64       //Tamaño del archivo
+	ContentType string      //Tipo de contenido (MIME) detectado por el remitente (vacío en el contenido cifrado)
+}
+
+//Función que obtiene los metadatos de un contenido a enviar. Solo los archivos regulares tienen metadatos (nil en otro
+//caso). El tipo de contenido se detecta a partir de los primeros bytes del archivo
+func readFileMetadata(content io.Reader) *FileMetadata {
+	file, isFile := content.(*os.File)
+	if !isFile {
+		return nil
+	}
+	fileInfo, statError := file.Stat()
+	if statError != nil || !fileInfo.Mode().IsRegular() {
+		return nil
+	}
+	var sniffBuffer []byte = make([]byte, CONTENT_SNIFF_LENGTH)
+	n, _ := file.ReadAt(sniffBuffer, 0)
+	return &FileMetadata{
+		ModTime:     fileInfo.ModTime(),
+		Mode:        fileInfo.Mode().Perm(),
+		Size:        fileInfo.Size(),
+		ContentType: http.DetectContentType(sniffBuffer[:n]),
+	}
+}
+
+//Función que crea el bloque de metadatos que se envía tras el nombre del archivo
+func createMetadataBlock(metadata *FileMetadata) []byte {
+	var contentType string = metadata.ContentType
+	if len(contentType) > MAX_CONTENT_TYPE_LENGTH {
+		contentType = ""
+	}
+	var block []byte = make([]byte, METADATA_LENGTH_FIELD+METADATA_FIXED_LENGTH, METADATA_LENGTH_FIELD+METADATA_FIXED_LENGTH+len(contentType))
+	binary.LittleEndian.PutUint16(block, uint16(METADATA_FIXED_LENGTH+len(contentType)))
+	binary.LittleEndian.PutUint64(block[2:], uint64(metadata.ModTime.UnixNano()))
+	binary.LittleEndian.PutUint32(block[10:], uint32(metadata.Mode.Perm()))
+	binary.LittleEndian.PutUint64(block[14:], uint64(metadata.Size))
+	block[22] = byte(len(contentType))
+	return append(block, contentType...)
+}
+
+//Función que lee el bloque de metadatos de un mensaje de envío. Retorna los metadatos y el bloque completo (que, si el
+//contenido está cifrado, se autentica junto con el nombre del archivo)
+func readMetadataBlock(reader io.Reader) (*FileMetadata, []byte, error) {
+	var block []byte = make([]byte, METADATA_LENGTH_FIELD)
+	_, readError := io.ReadFull(reader, block)
+	if readError != nil {
+		return nil, nil, readError
+	}
+	var length int = int(binary.LittleEndian.Uint16(block))
+	if length < METADATA_FIXED_LENGTH || length > MAX_METADATA_LENGTH {
+		return nil, nil, errInvalidMetadata
+	}
+	block = append(block, make([]byte, length)...)
+	_, readError = io.ReadFull(reader, block[METADATA_LENGTH_FIELD:])
+	if readError != nil {
+		return nil, nil, readError
+	}
+	var contentTypeLength int = int(block[22])
+	if METADATA_FIXED_LENGTH+contentTypeLength > length {
+		return nil, nil, errInvalidMetadata
+	}
+	var metadata *FileMetadata = &FileMetadata{
+		ModTime:     time.Unix(0, int64(binary.LittleEndian.Uint64(block[2:]))),
+		Mode:        os.FileMode(binary.LittleEndian.Uint32(block[10:])).Perm(),
+		Size:        int64(binary.LittleEndian.Uint64(block[14:])),
+		ContentType: string(block[23 : 23+contentTypeLength]),
+	}
+	if metadata.Size < 0 {
+		return nil, nil, errInvalidMetadata
+	}
+	//El tipo de contenido solo puede tener caracteres ASCII imprimibles
+	for _, character := range []byte(metadata.ContentType) {
+		if character < 0x20 || character > 0x7E {
+			return nil, nil, errInvalidMetadata
+		}
+	}
+	return metadata, block, nil
+}
+
+//Función que aplica la fecha de modificación y los permisos de un archivo recibido. De los permisos enviados solo se
+//aplican los de ejecución, sobre los permisos con los que se creó el archivo (que respetan la umask del proceso) y solo
+//para quienes pueden leerlo, de modo que el remitente no pueda dejar el archivo escribible por todos o ilegible
+func applyFileMetadata(path string, metadata *FileMetadata) error {
+	fileInfo, statError := os.Stat(path)
+	if statError != nil {
+		return statError
+	}
+	var mode os.FileMode = fileInfo.Mode().Perm()
+	//Los bits de lectura (0444) desplazados corresponden a los de ejecución (0111) de las mismas clases
+	var executable os.FileMode = metadata.Mode.Perm() & (mode >> 2) & 0111
+	chmodError := os.Chmod(path, mode|executable)
+	if chmodError != nil {
+		return chmodError
+	}
+	return os.Chtimes(path, time.Now(), metadata.ModTime)
+}
@@ -0,0 +1,49 @@
+package client
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestApplyFileMetadata(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file permissions are not supported on Windows")
+	}
+	var directory string = t.TempDir()
+	var modTime time.Time = time.Unix(1700000000, 0)
+	var tests = []struct {
+		sent      os.FileMode
+		defaultTo os.FileMode //Permisos con los que se creó el archivo (según la umask)
+		expected  os.FileMode
+	}{
+		{0644, 0644, 0644},
+		{0755, 0644, 0755},
+		{0777, 0644, 0755},
+		{0666, 0600, 0600},
+		{0000, 0644, 0644},
+		{0700, 0640, 0740},
+		{0111, 0600, 0700},
+		{0755, 0200, 0200},
+	}
+	for i, test := range tests {
+		var path string = filepath.Join(directory, "archivo"+string(rune('a'+i)))
+		writeError := os.WriteFile(path, nil, 0600)
+		if writeError == nil {
+			writeError = os.Chmod(path, test.defaultTo)
+		}
+		if writeError != nil {
+			t.Fatalf("could not create test file: %v", writeError)
+		}
+		applyError := applyFileMetadata(path, &FileMetadata{ModTime: modTime, Mode: test.sent})
+		if applyError != nil {
+			t.Fatalf("applyFileMetadata failed: %v", applyError)
+		}
+		fileInfo, _ := os.Stat(path)
+		if fileInfo.Mode().Perm() != test.expected || !fileInfo.ModTime().Equal(modTime) {
+			t.Errorf("sent mode %v over %v: got %v (modified %v); expected %v (modified %v)", test.sent, test.defaultTo, fileInfo.Mode().Perm(), fileInfo.ModTime(), test.expected, modTime)
+		}
+	}
+}
@@ -0,0 +1,400 @@
+package client
+
+//Archivo con el modo de recepción push: en vez de que el servidor se conecte al listener del cliente para entregar cada
+//archivo, el cliente mantiene abierta la conexión de suscripción y el servidor envía los archivos a través de ella.
+//Cada archivo viaja en un flujo (stream) identificado por un número, de modo que varios archivos pueden recibirse a la
+//vez: cada trama lleva el identificador del flujo y un fragmento de los datos, y una trama sin datos indica el fin del
+//flujo. El contenido de un flujo es un mensaje de envío (send o extended send) y la respuesta del cliente se envía por el
+//mismo flujo. Ambos extremos envían tramas keepalive para detectar conexiones caídas
+
+import (
+	"Client/protocol"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+//Constantes del modo de recepción push
+const STREAM_ID_LENGTH = 4                        //Longitud del identificador de un flujo
+const PUSH_FRAME_MAX_DATA = 64 * 1024             //Cantidad máxima de datos en una trama
+const PUSH_STREAM_QUEUE_LENGTH = 64               //Tramas que se encolan por flujo antes de dejar de leer la conexión
+const PUSH_STREAM_STALL_TIMEOUT = 5 * time.Second //Tiempo máximo que se deja de leer la conexión por un flujo con la cola llena
+const KEEPALIVE_INTERVAL = 15 * time.Second       //Intervalo entre las tramas keepalive enviadas por el cliente
+const KEEPALIVE_TIMEOUT = 3 * KEEPALIVE_INTERVAL  //Tiempo sin recibir tramas tras el cual se considera caída la conexión
+
+//Error que indica que el flujo fue cerrado por el cliente
+var errStreamClosed = errors.New("stream closed")
+
+//Error que indica que el flujo se reinició porque sus datos no se leyeron a tiempo
+var errStreamReset = errors.New("stream reset (data was not read in time)")
+
+//Conexión de suscripción en el modo push
+type pushSession struct {
+	client            *Client
+	ctx               context.Context //Contexto de las transferencias (se cancela al terminar el periodo de gracia)
+	sub               *subscription
+	connection        net.Conn
+	encoder           *protocol.Encoder
+	channel           Channel //Canal suscrito (ya resuelto)
+	handler           Handler
+	writeMutex        sync.Mutex //Las tramas de distintos flujos no deben intercalarse al escribirse
+	streamsMutex      sync.Mutex
+	streams           map[uint32]*pushStream
+	lastStreamID      uint32     //Identificador del último flujo abierto por el servidor (los identificadores son crecientes)
+	unsubscribed      int32      //Indica si ya se envió la cancelación de la suscripción
+	unsubscribeResult chan error //Resultado de la cancelación de la suscripción (respuesta del servidor)
+}
+
+//Flujo de la conexión de suscripción por el que se recibe un archivo
+type pushStream struct {
+	id        uint32
+	session   *pushSession
+	frames    chan []byte   //Datos recibidos del servidor (se cierra al recibir el fin del flujo)
+	closed    chan struct{} //Se cierra cuando el cliente termina de procesar el flujo
+	reset     chan struct{} //Se cierra si el flujo se reinicia porque su cola de tramas se llenó
+	closeOnce sync.Once
+	pending   []byte //Datos de la última trama que aún no se han leído
+}
+
+//Función que crea el contenido de una trama de un flujo
+func createPushFrameContent(streamID uint32, data []byte) []byte {
+	var content []byte = make([]byte, STREAM_ID_LENGTH+len(data))
+	binary.LittleEndian.PutUint32(content, streamID)
+	copy(content[STREAM_ID_LENGTH:], data)
+	return content
+}
+
+//Función que escribe una trama en la conexión de suscripción (las tramas no llevan el nombre del canal, pues la
+//conexión corresponde a un solo canal)
+func (s *pushSession) writeFrame(command protocol.Command, content []byte) error {
+	s.writeMutex.Lock()
+	defer s.writeMutex.Unlock()
+	return s.encoder.Encode(protocol.Message{Command: command, Channel: s.channel.Number, Content: content})
+}
+
+func (r *pushStream) Read(p []byte) (int, error) {
+	//Un flujo reiniciado no tiene su contenido completo, por lo que se descartan las tramas encoladas
+	select {
+	case <-r.reset:
+		return 0, errStreamReset
+	default:
+	}
+	if len(r.pending) == 0 {
+		select {
+		case data, ok := <-r.frames:
+			if !ok {
+				return 0, io.EOF
+			}
+			r.pending = data
+		case <-r.closed:
+			return 0, errStreamClosed
+		case <-r.reset:
+			return 0, errStreamReset
+		}
+	}
+	var n int = copy(p, r.pending)
+	r.pending = r.pending[n:]
+	return n, nil
+}
+
+func (r *pushStream) Write(p []byte) (int, error) {
+	var written int = 0
+	for len(p) > 0 {
+		var data []byte = p
+		if len(data) > PUSH_FRAME_MAX_DATA {
+			data = data[:PUSH_FRAME_MAX_DATA]
+		}
+		writeError := r.session.writeFrame(protocol.PUSH_DATA_COMMAND, createPushFrameContent(r.id, data))
+		if writeError != nil {
+			return written, writeError
+		}
+		written += len(data)
+		p = p[len(data):]
+	}
+	return written, nil
+}
+
+//Función que termina el flujo: se envía la trama de fin y se descartan los datos que sigan llegando
+func (r *pushStream) Close() error {
+	var closeError error
+	r.closeOnce.Do(func() {
+		close(r.closed)
+		r.session.streamsMutex.Lock()
+		delete(r.session.streams, r.id)
+		r.session.streamsMutex.Unlock()
+		closeError = r.session.writeFrame(protocol.PUSH_DATA_COMMAND, createPushFrameContent(r.id, nil))
+	})
+	return closeError
+}
+
+//Función para suscribirse a un canal en el modo push y recibir los archivos a través de la conexión de suscripción
+func (c *Client) subscribeWithPush(ctx context.Context, channel Channel, handler Handler) error {
+	var resolvedChannel Channel = c.resolveChannel(channel)
+	c.logln("Sending subscription request to server...")
+	//Se entabla la conexión con el servidor, que debe soportar el modo push
+	connection, capabilities, connectionError := c.connectToServer(ctx, false)
+	//Error check
+	if connectionError != nil {
+		return newError(STATUS_COMMUNICATION, "", "Error while connecting to server", connectionError)
+	}
+	if capabilities&CAP_SERVER_PUSH == 0 {
+		connection.Close()
+		return newError(STATUS_COMMUNICATION, "", "Server does not support push receive mode", nil)
+	}
+	supportError := checkChannelSupport(resolvedChannel, capabilities)
+	if supportError != nil {
+		connection.Close()
+		return supportError
+	}
+	//Se envía el mensaje de suscripción (el contenido va vacío, pues el servidor no se conecta al cliente)
+	writeError := protocol.NewEncoder(connection).Encode(createChannelMessage(protocol.SUBSCRIBE_PUSH_COMMAND, resolvedChannel, nil))
+	//Error check
+	if writeError != nil {
+		connection.Close()
+		return newError(STATUS_COMMUNICATION, "", "Error while sending message to server", writeError)
+	}
+	c.logln("Request sent. Awaiting server response...")
+	//Recibir respuesta del servidor
+	response, responseError := readServerResponse(connection)
+	//Error check
+	if responseError != nil {
+		connection.Close()
+		return responseError
+	}
+	//Interpretar respuesta
+	switch response.Command {
+	case protocol.OK_COMMAND:
+		c.logln("Client successfully subscribed to channel", channel)
+		c.logln("Awaiting incoming file transfers through the subscription connection...")
+	case protocol.ERROR_COMMAND:
+		connection.Close()
+		return newError(STATUS_COMMUNICATION, "", "", &ServerError{string(response.Content)})
+	default:
+		connection.Close()
+		return newError(STATUS_COMMUNICATION, "", fmt.Sprint("Invalid command received from server: ", response.Command), nil)
+	}
+
+	var sub *subscription = &subscription{channels: []Channel{channel}, stopped: make(chan struct{})}
+	//Las transferencias no se interrumpen al cancelar el contexto, sino al terminar el periodo de gracia
+	transfersCtx, cancelTransfers := context.WithCancel(context.Background())
+	defer cancelTransfers()
+	var session *pushSession = &pushSession{
+		client:            c,
+		ctx:               transfersCtx,
+		sub:               sub,
+		connection:        connection,
+		encoder:           protocol.NewEncoder(connection),
+		channel:           resolvedChannel,
+		handler:           handler,
+		streams:           map[uint32]*pushStream{},
+		unsubscribeResult: make(chan error, 1),
+	}
+	sub.session = session
+	registerError := c.registerSubscription(sub)
+	if registerError != nil {
+		session.unsubscribe()
+		connection.Close()
+		return registerError
+	}
+	defer c.unregisterSubscription(sub)
+	//Cancelar la suscripción (por la misma conexión) si se cancela el contexto
+	c.stopOnCancel(ctx, sub)
+	//Enviar tramas keepalive periódicamente para que el servidor sepa que el cliente sigue conectado
+	go func() {
+		var ticker *time.Ticker = time.NewTicker(KEEPALIVE_INTERVAL)
+		defer ticker.Stop()
+		for range ticker.C {
+			if session.writeFrame(protocol.KEEPALIVE_COMMAND, nil) != nil {
+				return
+			}
+		}
+	}()
+	//Ahora se atienden las transferencias. Tras cancelar la suscripción se sigue leyendo la conexión mientras terminan
+	//las transferencias en curso
+	var runResult chan error = make(chan error, 1)
+	go func() {
+		runResult <- session.run()
+		//Los flujos que quedaron abiertos ya no recibirán más datos
+		session.endStreams()
+	}()
+	var result error
+	var running bool = true
+	select {
+	case result = <-runResult:
+		running = false
+	case <-sub.stopped:
+	}
+	//Si se canceló la suscripción, se espera a que termine de cancelarse
+	if atomic.LoadInt32(&session.unsubscribed) == 1 {
+		<-sub.stopped
+		result = sub.stopError
+		if result == nil {
+			result = ctx.Err()
+		}
+	}
+	result = c.drainTransfers(sub, cancelTransfers, result)
+	connection.Close()
+	if running {
+		<-runResult
+	}
+	return result
+}
+
+//Función que lee las tramas de la conexión de suscripción y las reparte a los flujos correspondientes. Retorna nil si
+//la conexión terminó por la cancelación de la suscripción
+func (s *pushSession) run() error {
+	var decoder *protocol.Decoder = protocol.NewDecoder(s.connection)
+	for {
+		//Si el servidor no envía nada (ni siquiera keepalives) en un tiempo, se considera caída la conexión
+		s.connection.SetReadDeadline(time.Now().Add(KEEPALIVE_TIMEOUT))
+		frame, frameError := decoder.Decode(STREAM_ID_LENGTH + PUSH_FRAME_MAX_DATA)
+		if frameError == protocol.ErrContentTooLong || frameError == protocol.ErrInvalidLength {
+			return newError(STATUS_COMMUNICATION, "", "Server sent a frame that is too long", nil)
+		} else if frameError != nil {
+			return s.connectionLost(frameError)
+		}
+		var command protocol.Command = frame.Command
+		var content []byte = frame.Content
+		switch command {
+		case protocol.KEEPALIVE_COMMAND:
+			//Solo indica que la conexión sigue viva
+		case protocol.PUSH_DATA_COMMAND:
+			if len(content) < STREAM_ID_LENGTH {
+				return newError(STATUS_COMMUNICATION, "", "Server sent an invalid stream frame", nil)
+			}
+			s.dispatch(binary.LittleEndian.Uint32(content), content[STREAM_ID_LENGTH:])
+		case protocol.OK_COMMAND, protocol.ERROR_COMMAND:
+			//Respuesta a la cancelación de la suscripción (la conexión se sigue leyendo hasta que se cierre, pues pueden
+			//quedar transferencias en curso)
+			if atomic.LoadInt32(&s.unsubscribed) == 0 {
+				return newError(STATUS_COMMUNICATION, "", fmt.Sprint("Invalid command received from server: ", command), nil)
+			}
+			if command == protocol.ERROR_COMMAND {
+				s.answerUnsubscribe(newError(STATUS_COMMUNICATION, "", "", &ServerError{string(content)}))
+			} else {
+				s.answerUnsubscribe(nil)
+			}
+		default:
+			return newError(STATUS_COMMUNICATION, "", fmt.Sprint("Invalid command received from server: ", command), nil)
+		}
+	}
+}
+
+//Función que entrega los datos de una trama a su flujo. Un identificador nuevo abre un flujo, cuyo archivo se recibe
+//en una goroutine como si fuera una conexión del servidor
+func (s *pushSession) dispatch(streamID uint32, data []byte) {
+	s.streamsMutex.Lock()
+	stream, found := s.streams[streamID]
+	//Tras cancelar la suscripción no se abren flujos nuevos
+	if !found && streamID > s.lastStreamID && len(data) > 0 && atomic.LoadInt32(&s.unsubscribed) == 0 {
+		s.lastStreamID = streamID
+		stream = &pushStream{id: streamID, session: s, frames: make(chan []byte, PUSH_STREAM_QUEUE_LENGTH), closed: make(chan struct{}), reset: make(chan struct{})}
+		s.streams[streamID] = stream
+		found = true
+		s.client.startTransfer(s.ctx, s.sub, stream, s.handler)
+	}
+	//Al terminar el flujo, los datos que sigan llegando con su identificador se descartan
+	if found && len(data) == 0 {
+		delete(s.streams, streamID)
+	}
+	s.streamsMutex.Unlock()
+	//Los datos de flujos ya terminados se descartan
+	if !found {
+		return
+	}
+	if len(data) == 0 {
+		close(stream.frames)
+		return
+	}
+	select {
+	case stream.frames <- data:
+		return
+	case <-stream.closed:
+		return
+	default:
+	}
+	//Si la cola del flujo está llena, se deja de leer la conexión (deteniendo también a los demás flujos) solo por un
+	//tiempo: un flujo que no lee sus datos (por ejemplo, porque su handler espera a que termine otro archivo) podría
+	//detener para siempre al flujo del que depende. Pasado ese tiempo el flujo se reinicia: su transferencia falla y
+	//las tramas que sigan llegando se descartan
+	var timer *time.Timer = time.NewTimer(PUSH_STREAM_STALL_TIMEOUT)
+	defer timer.Stop()
+	select {
+	case stream.frames <- data:
+	case <-stream.closed:
+	case <-timer.C:
+		s.resetStream(stream)
+	}
+}
+
+//Función que reinicia un flujo cuya cola de tramas se llenó
+func (s *pushSession) resetStream(stream *pushStream) {
+	s.streamsMutex.Lock()
+	if s.streams[stream.id] == stream {
+		delete(s.streams, stream.id)
+	}
+	s.streamsMutex.Unlock()
+	s.client.logf("WARNING: Resetting stream %d (its data was not read in time)\n", stream.id)
+	close(stream.reset)
+}
+
+//Función que termina los flujos abiertos cuando deja de leerse la conexión de suscripción (su contenido queda
+//incompleto)
+func (s *pushSession) endStreams() {
+	s.streamsMutex.Lock()
+	defer s.streamsMutex.Unlock()
+	for streamID, stream := range s.streams {
+		close(stream.frames)
+		delete(s.streams, streamID)
+	}
+}
+
+//Función que cancela la suscripción a través de la conexión de suscripción. Si el servidor no responde a tiempo, se da
+//por cancelada de todas formas
+func (s *pushSession) unsubscribe() error {
+	s.client.logf("\nCancelling push subscription to channel %v...\n", s.channel)
+	atomic.StoreInt32(&s.unsubscribed, 1)
+	sendError := s.writeFrame(protocol.UNSUBSCRIBE_COMMAND, nil)
+	if sendError != nil {
+		return newError(STATUS_COMMUNICATION, "", "Error while sending message to server", sendError)
+	}
+	s.client.logln("Request sent. Awaiting server response...")
+	select {
+	case result := <-s.unsubscribeResult:
+		if result == nil {
+			s.client.logln("Client successfully unsubscribed from channel", s.channel)
+		}
+		return result
+	case <-time.After(HANDSHAKE_TIMEOUT):
+		s.client.logln("WARNING: Server did not answer the unsubscription request, closing connection")
+		return nil
+	}
+}
+
+//Función que entrega el resultado de la cancelación de la suscripción. Solo se considera el primero (por ejemplo, la
+//respuesta del servidor y no el cierre posterior de la conexión)
+func (s *pushSession) answerUnsubscribe(result error) {
+	select {
+	case s.unsubscribeResult <- result:
+	default:
+	}
+}
+
+//Función que obtiene el error correspondiente a la pérdida de la conexión de suscripción (salvo que ya se haya
+//cancelado la suscripción, en cuyo caso el servidor puede cerrar la conexión en vez de responder)
+func (s *pushSession) connectionLost(connectionError error) error {
+	if atomic.LoadInt32(&s.unsubscribed) == 1 {
+		s.answerUnsubscribe(nil)
+		return nil
+	}
+	if netError, ok := connectionError.(net.Error); ok && netError.Timeout() {
+		return newError(STATUS_TIMEOUT, "", "Connection with server timed out (no keepalive received)", nil)
+	}
+	return newError(STATUS_COMMUNICATION, "", "Connection with server lost", connectionError)
+}
@@ -0,0 +1,99 @@
+package client
+
+import (
+	"Client/protocol"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"sync"
+	"testing"
+	"time"
+)
+
+//Prueba que un flujo cuyo handler no lee sus datos (porque espera a que termine otro archivo, como WriterHandler) no
+//detenga para siempre la lectura de la conexión: el flujo se reinicia y el archivo del que depende se recibe completo
+func TestPushStalledStream(t *testing.T) {
+	const FRAME_DATA = 1024
+	var connection *fragmentingConn = &fragmentingConn{}
+	var channel Channel = NumberedChannel(1)
+	var sub *subscription = &subscription{channels: []Channel{channel}, stopped: make(chan struct{})}
+	var handlerMutex sync.Mutex
+	var started chan string = make(chan string, 2)
+	var session *pushSession = &pushSession{
+		client:     &Client{},
+		ctx:        context.Background(),
+		sub:        sub,
+		connection: connection,
+		encoder:    protocol.NewEncoder(connection),
+		channel:    channel,
+		streams:    map[uint32]*pushStream{},
+		handler: HandlerFunc(func(ctx context.Context, file *IncomingFile) error {
+			handlerMutex.Lock()
+			defer handlerMutex.Unlock()
+			started <- file.Name
+			_, copyError := io.Copy(io.Discard, file)
+			return copyError
+		}),
+	}
+	//Función que crea el mensaje de envío de un archivo con el contenido indicado
+	var sendMessage = func(filename string, size int) []byte {
+		return encodeMessages(t, protocol.Message{
+			Command: protocol.SEND_COMMAND,
+			Channel: channel.Number,
+			Content: append(createFilenameField(protocol.SEND_COMMAND, 0, []byte(filename)), make([]byte, size)...),
+		})
+	}
+	//Función que entrega un mensaje a un flujo en tramas de FRAME_DATA bytes (sin la trama de fin)
+	var dispatchFrames = func(streamID uint32, message []byte) {
+		for len(message) > 0 {
+			var data []byte = message
+			if len(data) > FRAME_DATA {
+				data = data[:FRAME_DATA]
+			}
+			session.dispatch(streamID, data)
+			message = message[len(data):]
+		}
+	}
+	var first []byte = sendMessage("primero.bin", 2*PUSH_STREAM_QUEUE_LENGTH*FRAME_DATA)
+	var second []byte = sendMessage("segundo.bin", 2*PUSH_STREAM_QUEUE_LENGTH*FRAME_DATA)
+	//El primer archivo toma el handler antes de que llegue el segundo
+	session.dispatch(1, first[:FRAME_DATA])
+	if name := <-started; name != "primero.bin" {
+		t.Fatalf("handler started with %q; expected primero.bin", name)
+	}
+	var done chan struct{} = make(chan struct{})
+	go func() {
+		dispatchFrames(2, second)
+		session.dispatch(2, nil)
+		dispatchFrames(1, first[FRAME_DATA:])
+		session.dispatch(1, nil)
+		sub.transfers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(PUSH_STREAM_STALL_TIMEOUT + 10*time.Second):
+		t.Fatalf("push session deadlocked")
+	}
+	//Separar las respuestas enviadas por cada flujo
+	var responses map[uint32]*bytes.Buffer = map[uint32]*bytes.Buffer{1: {}, 2: {}}
+	var decoder *protocol.Decoder = protocol.NewDecoder(&connection.output)
+	for {
+		frame, decodeError := decoder.Decode(STREAM_ID_LENGTH + PUSH_FRAME_MAX_DATA)
+		if decodeError == io.EOF {
+			break
+		} else if decodeError != nil {
+			t.Fatalf("invalid frame sent by client: %v", decodeError)
+		}
+		responses[binary.LittleEndian.Uint32(frame.Content)].Write(frame.Content[STREAM_ID_LENGTH:])
+	}
+	firstResponse, _ := readServerResponse(responses[1])
+	if firstResponse.Command != protocol.OK_COMMAND {
+		t.Errorf("first file response = %v %q; expected ok", firstResponse.Command, firstResponse.Content)
+	}
+	secondResponse, _ := readServerResponse(responses[2])
+	if secondResponse.Command != protocol.ERROR_COMMAND {
+		t.Errorf("stalled file response = %v %q; expected error", secondResponse.Command, secondResponse.Content)
+	}
+}
@@ -0,0 +1,321 @@
+package client
+
+//Archivo que contiene las funciones para recibir los archivos que el servidor entrega en un canal suscrito
+
+import (
+	"Client/protocol"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+//Función para recibir un archivo proveniente del servidor (a través de una conexión del servidor o de un flujo de la
+//conexión de suscripción en el modo push). El archivo se entrega al handler y se responde al servidor según el
+//resultado. Si se cancela el contexto, se cierra la conexión (interrumpiendo la transferencia) y no se responde.
+//Se aceptan los archivos enviados a cualquiera de los canales indicados. Retorna el nombre del archivo (vacío si no se
+//alcanzó a recibir) y si la transferencia se interrumpió
+func (c *Client) receiveFile(ctx context.Context, connection io.ReadWriteCloser, channels []Channel, handler Handler) (string, bool) {
+	//Asegurarse de que la conexión se cierre
+	defer connection.Close()
+	var stopWatching func() = closeOnCancel(ctx, connection)
+	defer stopWatching()
+	//Leer el mensaje hasta el inicio del contenido del archivo
+	file, transferError := c.readIncomingFile(connection, channels)
+	if transferError == nil {
+		//Ya se tiene el nombre del archivo, se muestra un mensaje (con el tipo de contenido, si el remitente lo indicó)
+		if file.Metadata != nil && file.Metadata.ContentType != "" {
+			c.logf("Receiving file %v (%v) from server...\n", file.Name, file.Metadata.ContentType)
+		} else {
+			c.logln("Receiving file", file.Name, "from server...")
+		}
+		transferError = handler.ReceiveFile(ctx, file)
+		//Si el handler no leyó todo el contenido, se lee el resto para verificarlo antes de responder
+		if transferError == nil {
+			transferError = file.finish()
+		}
+	}
+	//La conexión ya se cerró, por lo que no se puede responder al servidor
+	if ctx.Err() != nil {
+		if file != nil {
+			c.logln("Transfer of file", file.Name, "cancelled")
+			return file.Name, true
+		}
+		c.logln("File transfer cancelled")
+		return "", true
+	}
+	//Se responde por el canal del archivo (si no se alcanzó a leer, por el primero de la suscripción). Las respuestas no
+	//llevan el nombre del canal
+	var channel Channel = channels[0]
+	if file != nil {
+		channel = file.Channel
+	}
+	var exitStatus int = c.answerTransfer(connection, c.resolveChannel(channel).Number, file, transferError)
+	c.logf("Handled file transfer (status: %d)\n", exitStatus)
+	if file != nil {
+		return file.Name, false
+	}
+	return "", false
+}
+
+//Función que responde al servidor el resultado de una transferencia. Retorna el código que indica el resultado de
+//procesar la conexión (0 si el archivo se recibió correctamente)
+func (c *Client) answerTransfer(connection io.Writer, channel int8, file *IncomingFile, transferError error) int {
+	var response protocol.Message = protocol.Message{Channel: channel}
+	var exitStatus int = 0
+	//Si no se tienen los bytes previos al byte inicial, se pide al servidor continuar desde el último que se tiene
+	var offsetError *ResumeOffsetError
+	var receiveError *Error
+	if transferError == nil {
+		//Ya se descargó el archivo
+		c.logf("File %v received (%d bytes)\n", file.Name, file.Size)
+		response.Command = protocol.OK_COMMAND
+		response.Content = []byte("received")
+	} else if errors.As(transferError, &offsetError) {
+		c.logf("Requesting server to resume transfer of file %v from byte %d\n", file.Name, offsetError.Offset)
+		response.Command = protocol.RESUME_OFFSET_COMMAND
+		response.Content = createResumeOffset(offsetError.Offset)
+		exitStatus = STATUS_COMMUNICATION
+	} else {
+		//Los errores que no son del cliente provienen del handler
+		if !errors.As(transferError, &receiveError) {
+			receiveError = newError(STATUS_PROTOCOL, "file handling failed", "Error while handling received file", transferError)
+		}
+		c.logln("ERROR: " + receiveError.Error())
+		response.Command = protocol.ERROR_COMMAND
+		response.Content = []byte(receiveError.Reason)
+		exitStatus = receiveError.Status
+	}
+	err := protocol.NewEncoder(connection).Encode(response)
+	if err != nil {
+		c.logln("ERROR: Error while sending response to server: " + err.Error())
+		if transferError == nil {
+			exitStatus = STATUS_FILESYSTEM
+		}
+	}
+	return exitStatus
+}
+
+//Función que lee un mensaje de envío hasta el inicio del contenido del archivo, validando cada uno de sus campos
+func (c *Client) readIncomingFile(connection io.ReadWriter, channels []Channel) (*IncomingFile, error) {
+	//Leer el header del mensaje
+	var decoder *protocol.Decoder = protocol.NewDecoder(connection)
+	header, headerError := decoder.DecodeHeader()
+	//Error check
+	if headerError == protocol.ErrInvalidLength {
+		return nil, newError(STATUS_PROTOCOL, "invalid content length", "The client's message specified an invalid content length", nil)
+	} else if headerError != nil {
+		return nil, newError(STATUS_COMMUNICATION, "header read error", "Error while reading message header", headerError)
+	}
+	//Si el servidor inicia la conexión con un saludo, se le responde con las capacidades del cliente y se lee el
+	//header del mensaje que le sigue (un servidor antiguo envía directamente el mensaje)
+	if header.Command == protocol.HELLO_COMMAND {
+		_, helloError := answerHello(connection, header)
+		if helloError == nil {
+			header, headerError = decoder.DecodeHeader()
+		} else {
+			headerError = helloError
+		}
+		//Error check
+		if headerError != nil {
+			return nil, newError(STATUS_COMMUNICATION, "header read error", "Error while negotiating protocol with server", headerError)
+		}
+	}
+	var contentLength int64 = header.Length
+	//Comprobar validez de los 3 campos
+	//Comando (debe ser el comando send, o su versión con nombre de longitud variable)
+	if header.Command != protocol.SEND_COMMAND && header.Command != protocol.EXTENDED_SEND_COMMAND {
+		return nil, newError(STATUS_PROTOCOL, "invalid command", "Invalid command (should have value 1 for \"send\")", nil)
+	}
+	//Canal (debe ser uno de los suscritos). En los canales con nombre, el nombre del canal antecede al contenido
+	var channel Channel = NumberedChannel(header.Channel)
+	if header.Channel == NAMED_CHANNEL {
+		var channelFieldLength int64
+		var channelError error
+		channel, channelFieldLength, channelError = readChannelNameField(io.LimitReader(connection, contentLength))
+		if errors.Is(channelError, ErrInvalidChannelName) {
+			return nil, newError(STATUS_PROTOCOL, "invalid channel name", "The client's message specified an invalid channel name", channelError)
+		} else if channelError != nil {
+			return nil, newError(STATUS_COMMUNICATION, "channel read error", "Error while reading channel name", channelError)
+		}
+		contentLength -= channelFieldLength
+	}
+	var subscribedChannel Channel
+	var subscribed bool = false
+	for _, candidate := range channels {
+		if c.resolveChannel(candidate) == channel {
+			subscribedChannel = candidate
+			subscribed = true
+		}
+	}
+	if !subscribed {
+		return nil, newError(STATUS_PROTOCOL, "incorrect channel", "Received channel is not subscribed", nil)
+	}
+	//Longitud de contenido (debe ser mayor al tamaño del campo del nombre de archivo; en el formato extendido se
+	//verifica nuevamente al conocer la longitud del nombre)
+	var minimumContentLength int64 = FILENAME_MAX_LENGTH + 1
+	if header.Command == protocol.EXTENDED_SEND_COMMAND {
+		minimumContentLength = EXTENDED_FILENAME_HEADER_LENGTH + 1
+	}
+	if contentLength < minimumContentLength {
+		return nil, newError(STATUS_PROTOCOL, "invalid content length", "The client's message specified an invalid content length", nil)
+	}
+	//Leer el nombre del archivo (campo de longitud fija en el formato original, o prefijado por su longitud)
+	var filenameBuffer []byte
+	var filenameFieldLength int64
+	var sendFlags byte = 0 //Flags del formato extendido (el formato original no tiene)
+	var filenameError error
+	if header.Command == protocol.EXTENDED_SEND_COMMAND {
+		sendFlags, filenameBuffer, filenameError = readExtendedFilenameField(connection)
+		filenameFieldLength = EXTENDED_FILENAME_HEADER_LENGTH + int64(len(filenameBuffer))
+	} else {
+		filenameBuffer = make([]byte, FILENAME_MAX_LENGTH)
+		_, filenameError = io.ReadFull(connection, filenameBuffer)
+		filenameFieldLength = FILENAME_MAX_LENGTH
+	}
+	//Error check
+	if filenameError == errFilenameTooLong || filenameError == errUnsupportedFlags {
+		return nil, newError(STATUS_PROTOCOL, "invalid filename ("+filenameError.Error()+")", "The client's message specified an invalid file name field", filenameError)
+	} else if filenameError != nil {
+		return nil, newError(STATUS_COMMUNICATION, "filename read error", "Error while reading file name", filenameError)
+	}
+	//Si el mensaje incluye los metadatos del archivo, estos se envían justo después del nombre. Si el contenido está
+	//cifrado, los flags, el nombre (tal como se envió, antes de sanearlo) y los metadatos quedan autenticados
+	var metadata *FileMetadata
+	var authenticatedData []byte = append([]byte{sendFlags}, filenameBuffer...)
+	if sendFlags&FLAG_METADATA != 0 {
+		var metadataBlock []byte
+		var metadataError error
+		metadata, metadataBlock, metadataError = readMetadataBlock(io.LimitReader(connection, contentLength-filenameFieldLength))
+		if metadataError == errInvalidMetadata {
+			return nil, newError(STATUS_PROTOCOL, "invalid metadata", "The client's message specified invalid file metadata", metadataError)
+		} else if metadataError != nil {
+			return nil, newError(STATUS_COMMUNICATION, "header read error", "Error while reading file metadata", metadataError)
+		}
+		filenameFieldLength += int64(len(metadataBlock))
+		authenticatedData = append(authenticatedData, metadataBlock...)
+	}
+	//Si el mensaje es reanudable, después del nombre se envían los datos de reanudación
+	if sendFlags&FLAG_RESUMABLE != 0 {
+		filenameFieldLength += RESUME_BLOCK_LENGTH
+	}
+	//Un manifiesto se identifica por el nombre del directorio, no por una ruta
+	if sendFlags&FLAG_MANIFEST != 0 && sendFlags&FLAG_RELATIVE_PATH != 0 {
+		return nil, newError(STATUS_PROTOCOL, "invalid filename ("+errUnsupportedFlags.Error()+")", "The client's message specified an invalid file name field", errUnsupportedFlags)
+	}
+	//El contenido enviado por bloques no puede reanudarse ni cifrarse, pues su tamaño no se conoce de antemano
+	var chunked bool = sendFlags&FLAG_CHUNKED != 0
+	if chunked && sendFlags&(FLAG_RESUMABLE|FLAG_ENCRYPTED) != 0 {
+		return nil, newError(STATUS_PROTOCOL, "invalid filename ("+errUnsupportedFlags.Error()+")", "The client's message specified an invalid file name field", errUnsupportedFlags)
+	}
+	//Si el contenido está cifrado, después del nombre se envían los datos de cifrado (no puede ser reanudable)
+	if sendFlags&FLAG_ENCRYPTED != 0 {
+		filenameFieldLength += ENCRYPTION_BLOCK_LENGTH
+		if sendFlags&FLAG_RESUMABLE != 0 {
+			return nil, newError(STATUS_PROTOCOL, "invalid filename ("+errUnsupportedFlags.Error()+")", "The client's message specified an invalid file name field", errUnsupportedFlags)
+		}
+	}
+	//Si el mensaje incluye el hash del archivo, este se envía al final del contenido
+	var trailerLength int64 = 0
+	if sendFlags&FLAG_SHA256_TRAILER != 0 {
+		trailerLength = sha256.Size
+	}
+	//El nombre del archivo (y el hash) deben caber en el contenido del mensaje. En el envío por bloques, el contenido del
+	//mensaje es solo el nombre, y el hash llega al final de los bloques
+	if chunked && contentLength != filenameFieldLength {
+		return nil, newError(STATUS_PROTOCOL, "invalid content length", "The client's message specified an invalid content length", nil)
+	} else if !chunked && contentLength < filenameFieldLength+trailerLength {
+		return nil, newError(STATUS_PROTOCOL, "invalid content length", "The client's message specified an invalid content length", nil)
+	}
+	//Parsear y validar el nombre del archivo (en el formato original, bytes no utilizados se llenan con el caracter
+	//\x00). Se rechazan los nombres que podrían escribir fuera del directorio de descarga. Los archivos de un directorio
+	//tienen una ruta relativa, cuyos componentes se validan por separado
+	var filename string
+	if sendFlags&FLAG_RELATIVE_PATH != 0 {
+		filename, filenameError = sanitizeRelativePath(string(filenameBuffer))
+	} else if header.Command == protocol.EXTENDED_SEND_COMMAND {
+		filename, filenameError = sanitizeFilename(string(filenameBuffer))
+	} else {
+		filename, filenameError = parseFilenameField(filenameBuffer)
+	}
+	//Comprobar que el nombre del archivo no esté vacío
+	if filenameError == errEmptyFilename {
+		return nil, newError(STATUS_PROTOCOL, "empty filename", "The client's message specified an empty file name", nil)
+	} else if filenameError != nil {
+		return nil, newError(STATUS_PROTOCOL, "invalid filename ("+filenameError.Error()+")", fmt.Sprintf("The client's message specified an invalid file name %q", strings.TrimRight(string(filenameBuffer), "\x00")), filenameError)
+	}
+	//Con una frase secreta configurada solo se aceptan archivos cifrados, de modo que el servidor no pueda entregar
+	//contenido propio quitando el flag de cifrado
+	if c.Passphrase != nil && sendFlags&FLAG_ENCRYPTED == 0 {
+		return nil, newError(STATUS_INTEGRITY, "unencrypted content", "File "+filename+" is not encrypted, but a passphrase was configured", nil)
+	}
+	//Cantidad de bytes del archivo incluidos en el mensaje
+	var fileSize int64 = contentLength - filenameFieldLength - trailerLength
+	var file *IncomingFile = &IncomingFile{
+		Name:          filename,
+		Channel:       subscribedChannel,
+		Size:          fileSize,
+		Manifest:      sendFlags&FLAG_MANIFEST != 0,
+		Metadata:      metadata,
+		reader:        connection,
+		connection:    connection,
+		hash:          sha256.New(),
+		trailerLength: trailerLength,
+		remaining:     fileSize,
+	}
+	if chunked {
+		file.Size = -1
+		file.remaining = 0
+		file.chunks = &chunkedReader{decoder: decoder, reader: connection, maxLength: trailerLength}
+	}
+	//Leer los datos de reanudación (identificador, byte inicial y tamaño total del archivo)
+	if sendFlags&FLAG_RESUMABLE != 0 {
+		resume, resumeError := readResumeBlock(connection)
+		//Error check
+		if resumeError != nil {
+			return nil, newError(STATUS_COMMUNICATION, "header read error", "Error while reading transfer resume data", resumeError)
+		}
+		//El contenido enviado debe completar el archivo a partir del byte inicial
+		if resume.offset < 0 || resume.offset+fileSize != resume.size {
+			return nil, newError(STATUS_PROTOCOL, "invalid content length", "The client's message specified an invalid content length", nil)
+		}
+		file.TransferID = resume.transferID
+		file.Offset = resume.offset
+		file.Size = resume.size
+	}
+	//Si el contenido está cifrado de extremo a extremo, se descifra conforme se lee de la conexión
+	if sendFlags&FLAG_ENCRYPTED != 0 {
+		//Sin la frase secreta del canal no es posible descifrar el archivo
+		if c.Passphrase == nil {
+			return nil, newError(STATUS_PROTOCOL, "encrypted content not supported", "File "+filename+" is encrypted, but no passphrase was configured", nil)
+		}
+		//Leer los datos de cifrado (sal y prefijo de los nonces)
+		var encryptionBlock []byte = make([]byte, ENCRYPTION_BLOCK_LENGTH)
+		_, encryptionError := io.ReadFull(connection, encryptionBlock)
+		//Error check
+		if encryptionError != nil {
+			return nil, newError(STATUS_COMMUNICATION, "header read error", "Error while reading file encryption data", encryptionError)
+		}
+		//El tamaño del archivo es el del contenido sin los datos de autenticación de cada bloque
+		plaintextSize, sizeError := decryptedSize(fileSize)
+		if sizeError == nil {
+			file.decrypter, sizeError = newDecryptingReader(connection, c.Passphrase, channel, authenticatedData, encryptionBlock, fileSize)
+		}
+		//Error check
+		if sizeError != nil {
+			return nil, newError(STATUS_PROTOCOL, "invalid content length", "The client's message specified an invalid content length", nil)
+		}
+		file.Encrypted = true
+		file.reader = file.decrypter
+		file.Size = plaintextSize
+		file.remaining = plaintextSize
+	}
+	//El tamaño indicado en los metadatos debe coincidir con el del archivo (en el envío por bloques, se verifica al
+	//terminar de leerlo)
+	if metadata != nil && !chunked && metadata.Size != file.Size {
+		return nil, newError(STATUS_PROTOCOL, "invalid metadata", "The client's message specified invalid file metadata", errInvalidMetadata)
+	}
+	return file, nil
+}
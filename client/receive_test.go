@@ -0,0 +1,298 @@
+package client
+
+import (
+	"Client/protocol"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+//Conexión en memoria que, si fragment es true, entrega los datos de a un byte por lectura (como puede ocurrir con una
+//conexión TCP real), para verificar que ninguna lectura asuma que recibe un mensaje completo
+type fragmentingConn struct {
+	input    io.Reader
+	output   bytes.Buffer
+	fragment bool
+}
+
+func (c *fragmentingConn) Read(p []byte) (int, error) {
+	if c.fragment && len(p) > 1 {
+		p = p[:1]
+	}
+	return c.input.Read(p)
+}
+
+func (c *fragmentingConn) Write(p []byte) (int, error)        { return c.output.Write(p) }
+func (c *fragmentingConn) Close() error                       { return nil }
+func (c *fragmentingConn) LocalAddr() net.Addr                { return &net.TCPAddr{} }
+func (c *fragmentingConn) RemoteAddr() net.Addr               { return &net.TCPAddr{} }
+func (c *fragmentingConn) SetDeadline(t time.Time) error      { return nil }
+func (c *fragmentingConn) SetReadDeadline(t time.Time) error  { return nil }
+func (c *fragmentingConn) SetWriteDeadline(t time.Time) error { return nil }
+
+//Función que codifica los mensajes indicados en un único buffer
+func encodeMessages(t *testing.T, messages ...protocol.Message) []byte {
+	var buffer bytes.Buffer
+	var encoder *protocol.Encoder = protocol.NewEncoder(&buffer)
+	for _, message := range messages {
+		encodeError := encoder.Encode(message)
+		if encodeError != nil {
+			t.Fatalf("Encode failed: %v", encodeError)
+		}
+	}
+	return buffer.Bytes()
+}
+
+//Resultado de leer un archivo recibido
+type receivedFileResult struct {
+	Name     string
+	Channel  Channel
+	Size     int64
+	Manifest bool
+	Metadata *FileMetadata
+	Content  []byte
+	Written  []byte //Bytes escritos en la conexión (la respuesta al saludo)
+	Error    string
+}
+
+//Función que lee con el cliente indicado un archivo recibido (incluyendo su contenido) de la conexión indicada
+func readFileFrom(c *Client, connection *fragmentingConn, channels []Channel) receivedFileResult {
+	var result receivedFileResult
+	file, readError := c.readIncomingFile(connection, channels)
+	if readError == nil {
+		result.Content, readError = io.ReadAll(file)
+	}
+	if readError == nil {
+		readError = file.finish()
+	}
+	if file != nil {
+		result.Name, result.Channel, result.Size = file.Name, file.Channel, file.Size
+		result.Manifest, result.Metadata = file.Manifest, file.Metadata
+	}
+	if readError != nil {
+		result.Error = readError.Error()
+	}
+	result.Written = connection.output.Bytes()
+	return result
+}
+
+func TestReadIncomingFileFragmented(t *testing.T) {
+	var content []byte = bytes.Repeat([]byte("contenido del archivo "), 100)
+	var contentHash [sha256.Size]byte = sha256.Sum256(content)
+	var metadata *FileMetadata = &FileMetadata{ModTime: time.Unix(0, 1700000000123456789), Mode: 0640, Size: int64(len(content)), ContentType: "text/plain; charset=utf-8"}
+	var builds Channel = NamedChannel("builds")
+	//Mensaje en el formato original
+	var original []byte = encodeMessages(t, protocol.Message{
+		Command: protocol.SEND_COMMAND,
+		Channel: 1,
+		Content: append(createFilenameField(protocol.SEND_COMMAND, 0, []byte("informe.txt")), content...),
+	})
+	//Saludo seguido de un mensaje extendido a un canal con nombre, con metadatos, ruta relativa y hash
+	var extendedFlags byte = FLAG_SHA256_TRAILER | FLAG_RELATIVE_PATH | FLAG_METADATA
+	var extendedContent []byte = append(createChannelNameField(builds), createFilenameField(protocol.EXTENDED_SEND_COMMAND, extendedFlags, []byte("dir/sub/datos.bin"))...)
+	extendedContent = append(extendedContent, createMetadataBlock(metadata)...)
+	extendedContent = append(extendedContent, content...)
+	extendedContent = append(extendedContent, contentHash[:]...)
+	var extended []byte = encodeMessages(t,
+		createHelloMessage(),
+		protocol.Message{Command: protocol.EXTENDED_SEND_COMMAND, Channel: NAMED_CHANNEL, Content: extendedContent},
+	)
+	//Mensaje enviado por bloques
+	var chunked []byte = encodeMessages(t,
+		protocol.Message{Command: protocol.EXTENDED_SEND_COMMAND, Channel: 2, Content: createFilenameField(protocol.EXTENDED_SEND_COMMAND, FLAG_CHUNKED|FLAG_SHA256_TRAILER, []byte("stdin"))},
+		protocol.Message{Command: protocol.STREAM_DATA_COMMAND, Channel: 2, Content: content[:1000]},
+		protocol.Message{Command: protocol.STREAM_DATA_COMMAND, Channel: 2, Content: content[1000:]},
+		protocol.Message{Command: protocol.STREAM_END_COMMAND, Channel: 2, Content: contentHash[:]},
+	)
+	//Mensaje con el hash alterado
+	var corrupted []byte = append([]byte{}, extended...)
+	corrupted[len(corrupted)-1] ^= 0xFF
+	var tests = []struct {
+		description string
+		input       []byte
+		channels    []Channel
+		name        string
+		size        int64
+	}{
+		{"original send", original, []Channel{NumberedChannel(1)}, "informe.txt", int64(len(content))},
+		{"extended send", extended, []Channel{NumberedChannel(1), builds}, "dir/sub/datos.bin", int64(len(content))},
+		{"chunked send", chunked, []Channel{NumberedChannel(2)}, "stdin", int64(len(content))},
+		{"checksum mismatch", corrupted, []Channel{builds}, "dir/sub/datos.bin", int64(len(content))},
+		{"truncated message", original[:len(original)-10], []Channel{NumberedChannel(1)}, "informe.txt", int64(len(content))},
+	}
+	for _, test := range tests {
+		var expected receivedFileResult = readFileFrom(&Client{}, &fragmentingConn{input: bytes.NewReader(test.input)}, test.channels)
+		var fragmented receivedFileResult = readFileFrom(&Client{}, &fragmentingConn{input: bytes.NewReader(test.input), fragment: true}, test.channels)
+		if !reflect.DeepEqual(fragmented, expected) {
+			t.Errorf("%s: fragmented read = %+v; unfragmented read = %+v", test.description, fragmented, expected)
+		}
+		if expected.Name != test.name || expected.Size != test.size {
+			t.Errorf("%s: received %q (%d bytes); expected %q (%d bytes)", test.description, expected.Name, expected.Size, test.name, test.size)
+		}
+		if expected.Error == "" && !bytes.Equal(expected.Content, content) {
+			t.Errorf("%s: received content doesn't match sent content", test.description)
+		}
+	}
+	//El saludo se responde, los metadatos se leen y los errores de integridad se detectan
+	if result := readFileFrom(&Client{}, &fragmentingConn{input: bytes.NewReader(extended), fragment: true}, []Channel{builds}); !bytes.Equal(result.Written, encodeMessages(t, createHelloMessage())) || !reflect.DeepEqual(result.Metadata, metadata) || result.Channel != builds {
+		t.Errorf("extended send: unexpected result %+v", result)
+	}
+	if result := readFileFrom(&Client{}, &fragmentingConn{input: bytes.NewReader(corrupted), fragment: true}, []Channel{builds}); result.Error == "" {
+		t.Errorf("checksum mismatch: file was accepted")
+	}
+}
+
+//Función que cifra un contenido como lo hace sendAttempt (con metadatos). Retorna el inicio del contenido del mensaje
+//de envío (campo del nombre, metadatos y datos de cifrado) y los bloques cifrados
+func encryptContent(t *testing.T, passphrase []byte, channel Channel, filename string, content []byte) ([]byte, [][]byte) {
+	var flags byte = FLAG_ENCRYPTED | FLAG_METADATA
+	var metadataBlock []byte = createMetadataBlock(&FileMetadata{ModTime: time.Unix(1700000000, 0), Mode: 0644, Size: int64(len(content))})
+	var authenticatedData []byte = append(append([]byte{flags}, filename...), metadataBlock...)
+	var ciphertext bytes.Buffer
+	encrypter, encryptionBlock, encryptionError := newEncryptingWriter(&ciphertext, passphrase, channel, authenticatedData)
+	if encryptionError == nil {
+		_, encryptionError = encrypter.Write(content)
+	}
+	if encryptionError == nil {
+		encryptionError = encrypter.Close()
+	}
+	if encryptionError != nil {
+		t.Fatalf("could not encrypt content: %v", encryptionError)
+	}
+	var prefix []byte = append(createFilenameField(protocol.EXTENDED_SEND_COMMAND, flags, []byte(filename)), metadataBlock...)
+	prefix = append(prefix, encryptionBlock...)
+	var chunks [][]byte
+	for ciphertext.Len() > 0 {
+		chunks = append(chunks, ciphertext.Next(ENCRYPTION_CHUNK_SIZE+ENCRYPTION_TAG_LENGTH))
+	}
+	return prefix, chunks
+}
+
+func TestReadIncomingFileEncrypted(t *testing.T) {
+	var passphrase []byte = []byte("frase secreta del canal")
+	var content []byte = bytes.Repeat([]byte("contenido cifrado "), (2*ENCRYPTION_CHUNK_SIZE+1000)/18)
+	var prefix, chunks = encryptContent(t, passphrase, NumberedChannel(1), "informe.txt", content)
+	var _, otherChannelChunks = encryptContent(t, passphrase, NumberedChannel(2), "informe.txt", content)
+	//Función que crea el mensaje de envío cifrado al canal 1 con los bloques indicados
+	var message = func(prefix []byte, chunks ...[]byte) []byte {
+		return encodeMessages(t, protocol.Message{Command: protocol.EXTENDED_SEND_COMMAND, Channel: 1, Content: append(append([]byte{}, prefix...), bytes.Join(chunks, nil)...)})
+	}
+	var tampered []byte = append([]byte{}, chunks[1]...)
+	tampered[100] ^= 0x01
+	var flippedFlags []byte = append([]byte{}, prefix...)
+	flippedFlags[0] |= FLAG_RELATIVE_PATH
+	if len(chunks) != 3 {
+		t.Fatalf("content was encrypted in %d chunks; expected 3", len(chunks))
+	}
+	var tests = []struct {
+		description string
+		input       []byte
+		passphrase  []byte
+		accepted    bool
+	}{
+		{"round trip", message(prefix, chunks...), passphrase, true},
+		{"tampered chunk", message(prefix, chunks[0], tampered, chunks[2]), passphrase, false},
+		{"truncated content", message(prefix, chunks[0], chunks[1]), passphrase, false},
+		{"reordered chunks", message(prefix, chunks[1], chunks[0], chunks[2]), passphrase, false},
+		{"altered flags", message(flippedFlags, chunks...), passphrase, false},
+		{"wrong passphrase", message(prefix, chunks...), []byte("otra frase secreta"), false},
+		{"wrong channel", message(prefix, otherChannelChunks...), passphrase, false},
+	}
+	for _, test := range tests {
+		var c *Client = &Client{Passphrase: test.passphrase}
+		var expected receivedFileResult = readFileFrom(c, &fragmentingConn{input: bytes.NewReader(test.input)}, []Channel{NumberedChannel(1)})
+		var fragmented receivedFileResult = readFileFrom(c, &fragmentingConn{input: bytes.NewReader(test.input), fragment: true}, []Channel{NumberedChannel(1)})
+		if !reflect.DeepEqual(fragmented, expected) {
+			t.Errorf("%s: fragmented read = %+v; unfragmented read = %+v", test.description, fragmented, expected)
+		}
+		if test.accepted && (expected.Error != "" || !bytes.Equal(expected.Content, content) || expected.Size != int64(len(content))) {
+			t.Errorf("%s: received %d bytes (size %d, error %q); expected the sent content", test.description, len(expected.Content), expected.Size, expected.Error)
+		} else if !test.accepted && expected.Error == "" {
+			t.Errorf("%s: file was accepted", test.description)
+		}
+	}
+}
+
+//Prueba que un cliente con frase secreta rechace los archivos sin cifrar (en cualquier formato) y responda con un error
+func TestReceiveFileUnencrypted(t *testing.T) {
+	var content []byte = []byte("contenido en claro")
+	var tests = map[string][]byte{
+		"original send": encodeMessages(t, protocol.Message{
+			Command: protocol.SEND_COMMAND,
+			Channel: 1,
+			Content: append(createFilenameField(protocol.SEND_COMMAND, 0, []byte("informe.txt")), content...),
+		}),
+		"extended send": encodeMessages(t, protocol.Message{
+			Command: protocol.EXTENDED_SEND_COMMAND,
+			Channel: 1,
+			Content: append(createFilenameField(protocol.EXTENDED_SEND_COMMAND, 0, []byte("informe.txt")), content...),
+		}),
+		"chunked send": encodeMessages(t,
+			protocol.Message{Command: protocol.EXTENDED_SEND_COMMAND, Channel: 1, Content: createFilenameField(protocol.EXTENDED_SEND_COMMAND, FLAG_CHUNKED, []byte("stdin"))},
+			protocol.Message{Command: protocol.STREAM_DATA_COMMAND, Channel: 1, Content: content},
+			protocol.Message{Command: protocol.STREAM_END_COMMAND, Channel: 1, Content: []byte{}},
+		),
+	}
+	for description, input := range tests {
+		var c *Client = &Client{Passphrase: []byte("frase secreta del canal")}
+		var connection *fragmentingConn = &fragmentingConn{input: bytes.NewReader(input)}
+		var handler HandlerFunc = func(ctx context.Context, file *IncomingFile) error {
+			t.Errorf("%s: unencrypted file %s was handled", description, file.Name)
+			return nil
+		}
+		name, interrupted := c.receiveFile(context.Background(), connection, []Channel{NumberedChannel(1)}, handler)
+		response, responseError := readServerResponse(&connection.output)
+		if name != "" || interrupted || responseError != nil || response.Command != protocol.ERROR_COMMAND || string(response.Content) != "unencrypted content" {
+			t.Errorf("%s: receiveFile = %q, %v; response %v %q (%v); expected an unencrypted content error", description, name, interrupted, response.Command, response.Content, responseError)
+		}
+	}
+	var c *Client = &Client{Passphrase: []byte("frase secreta del canal")}
+	_, readError := c.readIncomingFile(&fragmentingConn{input: bytes.NewReader(tests["original send"])}, []Channel{NumberedChannel(1)})
+	var receiveError *Error
+	if !errors.As(readError, &receiveError) || receiveError.Status != STATUS_INTEGRITY {
+		t.Errorf("readIncomingFile = %v; expected an integrity error", readError)
+	}
+}
+
+func TestReadServerResponseFragmented(t *testing.T) {
+	var tests = [][]byte{
+		encodeMessages(t, protocol.Message{Command: protocol.OK_COMMAND, Channel: 1, Content: []byte("received")}),
+		encodeMessages(t, protocol.Message{Command: protocol.ERROR_COMMAND, Channel: 2, Content: []byte("channel not found")}),
+		encodeMessages(t, protocol.Message{Command: protocol.OK_COMMAND, Channel: 1, Content: make([]byte, BUFFER_SIZE+1)}),
+		encodeMessages(t, protocol.Message{Command: protocol.OK_COMMAND, Channel: 1, Content: []byte("received")})[:12],
+	}
+	for _, input := range tests {
+		expected, expectedError := readServerResponse(&fragmentingConn{input: bytes.NewReader(input)})
+		response, responseError := readServerResponse(&fragmentingConn{input: bytes.NewReader(input), fragment: true})
+		if !reflect.DeepEqual(response, expected) || !reflect.DeepEqual(responseError, expectedError) {
+			t.Errorf("readServerResponse(%x) = %v, %v; unfragmented: %v, %v", input, response, responseError, expected, expectedError)
+		}
+	}
+}
+
+func TestDecodeFragmented(t *testing.T) {
+	var messages = []protocol.Message{
+		createHelloMessage(),
+		{Command: protocol.PUSH_DATA_COMMAND, Channel: 3, Content: bytes.Repeat([]byte{0xAB}, BUFFER_SIZE)},
+		{Command: protocol.KEEPALIVE_COMMAND, Channel: 3, Content: []byte{}},
+		{Command: protocol.ERROR_COMMAND, Channel: 3, Content: []byte("fin")},
+	}
+	var input []byte = encodeMessages(t, messages...)
+	var decoder *protocol.Decoder = protocol.NewDecoder(&fragmentingConn{input: bytes.NewReader(input), fragment: true})
+	for _, expected := range messages {
+		message, decodeError := decoder.Decode(BUFFER_SIZE)
+		if decodeError != nil || !reflect.DeepEqual(message, expected) {
+			t.Fatalf("Decode = %v (%d bytes), %v; expected %v (%d bytes)", message.Command, len(message.Content), decodeError, expected.Command, len(expected.Content))
+		}
+	}
+	_, decodeError := decoder.Decode(BUFFER_SIZE)
+	if decodeError != io.EOF {
+		t.Errorf("Decode at end of input = %v; expected io.EOF", decodeError)
+	}
+}
@@ -0,0 +1,111 @@
+package client
+
+//Archivo con las funciones para reanudar transferencias interrumpidas (tanto al enviar como al recibir archivos)
+
+import (
+	"Client/protocol"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+//Constantes de la reanudación de transferencias
+const TRANSFER_ID_LENGTH = 16       //Longitud del identificador de una transferencia
+const RESUME_BLOCK_LENGTH = 32      //Longitud de los datos de reanudación: identificador + byte inicial + tamaño total
+const RETRY_DELAY = 1 * time.Second //Espera base entre reintentos de envío
+const DEFAULT_RETRIES = 3           //Cantidad de reintentos por defecto al fallar la conexión durante un envío
+
+//Datos de reanudación incluidos en un mensaje de envío
+type resumeBlock struct {
+	transferID []byte //Identificador de la transferencia
+	offset     int64  //Byte del archivo a partir del cual se envía el contenido
+	size       int64  //Tamaño total del archivo
+}
+
+//Error que indica que no se tienen los bytes previos al byte desde el cual se envía el contenido. Al retornarlo desde un
+//Handler, se pide al servidor continuar la transferencia desde el byte indicado
+type ResumeOffsetError struct {
+	Offset int64 //Cantidad de bytes del archivo que ya se tienen
+}
+
+func (e *ResumeOffsetError) Error() string {
+	return fmt.Sprintf("partial file has %d bytes", e.Offset)
+}
+
+//Función que crea los datos de reanudación de un mensaje de envío
+func createResumeBlock(transferID []byte, offset int64, size int64) []byte {
+	var block []byte = make([]byte, RESUME_BLOCK_LENGTH)
+	copy(block, transferID)
+	binary.LittleEndian.PutUint64(block[TRANSFER_ID_LENGTH:], uint64(offset))
+	binary.LittleEndian.PutUint64(block[TRANSFER_ID_LENGTH+8:], uint64(size))
+	return block
+}
+
+//Función que lee los datos de reanudación de un mensaje de envío
+func readResumeBlock(reader io.Reader) (resumeBlock, error) {
+	var buffer []byte = make([]byte, RESUME_BLOCK_LENGTH)
+	_, readError := io.ReadFull(reader, buffer)
+	if readError != nil {
+		return resumeBlock{}, readError
+	}
+	return resumeBlock{
+		transferID: buffer[:TRANSFER_ID_LENGTH],
+		offset:     int64(binary.LittleEndian.Uint64(buffer[TRANSFER_ID_LENGTH:])),
+		size:       int64(binary.LittleEndian.Uint64(buffer[TRANSFER_ID_LENGTH+8:])),
+	}, nil
+}
+
+//Función que crea el contenido de un mensaje con el byte desde el cual continuar una transferencia
+func createResumeOffset(offset int64) []byte {
+	var buffer []byte = make([]byte, 8)
+	binary.LittleEndian.PutUint64(buffer, uint64(offset))
+	return buffer
+}
+
+//Función que parsea el contenido de un mensaje con el byte desde el cual continuar una transferencia
+func parseResumeOffset(content []byte) (int64, error) {
+	if len(content) != 8 {
+		return 0, errors.New("invalid resume offset length")
+	}
+	var offset int64 = int64(binary.LittleEndian.Uint64(content))
+	if offset < 0 {
+		return 0, errors.New("negative resume offset")
+	}
+	return offset, nil
+}
+
+//Función que consulta al servidor cuántos bytes de una transferencia interrumpida recibió (el canal ya está resuelto)
+func (c *Client) queryResumeOffset(ctx context.Context, channel Channel, transferID []byte) (int64, error) {
+	connection, capabilities, connectionError := c.connectToServer(ctx, false)
+	if connectionError != nil {
+		return 0, connectionError
+	}
+	defer connection.Close()
+	supportError := checkChannelSupport(channel, capabilities)
+	if supportError != nil {
+		return 0, supportError
+	}
+	var stopWatching func() = closeOnCancel(ctx, connection)
+	defer stopWatching()
+	writeError := protocol.NewEncoder(connection).Encode(createChannelMessage(protocol.RESUME_QUERY_COMMAND, channel, transferID))
+	if writeError != nil {
+		return 0, writeError
+	}
+	response, readError := protocol.NewDecoder(connection).Decode(BUFFER_SIZE)
+	if readError == protocol.ErrContentTooLong {
+		return 0, errors.New("response is too long")
+	} else if readError != nil {
+		return 0, readError
+	}
+	switch response.Command {
+	case protocol.RESUME_OFFSET_COMMAND:
+		return parseResumeOffset(response.Content)
+	case protocol.ERROR_COMMAND:
+		return 0, errors.New("server error (" + string(response.Content) + ")")
+	default:
+		return 0, fmt.Errorf("invalid command received from server: %v", response.Command)
+	}
+}
@@ -0,0 +1,347 @@
+package client
+
+//Archivo que contiene las funciones para enviar archivos a un canal a través del servidor
+
+import (
+	"Client/protocol"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net"
+	"os"
+	"time"
+)
+
+//Resultado fallido de un intento de envío de un archivo
+type sendFailure struct {
+	err     *Error //Error a retornar si no se reintenta el envío
+	network bool   //Indica si falló la conexión (el envío podría continuarse si el servidor soporta reanudación)
+	offset  int64  //Byte desde el cual continuar indicado por el servidor (-1 si se debe consultar)
+}
+
+//Función que crea el resultado fallido de un intento de envío
+func newSendFailure(status int, message string, err error, network bool) *sendFailure {
+	return &sendFailure{newError(status, "", message, err), network, -1}
+}
+
+//Función para enviar un archivo a un canal con el nombre indicado. Si el contenido no permite búsquedas (io.Seeker, o
+//un *os.File que no es un archivo regular, como la entrada estándar), se envía por bloques conforme se lee si el
+//servidor lo soporta (ver chunked.go); si no, se copia primero a un archivo temporal para conocer su tamaño. Si el servidor soporta la reanudación de transferencias,
+//ante una caída de la conexión se reconecta (hasta c.Retries veces) y continúa desde el último byte confirmado. Si se
+//cancela el contexto, se cierra la conexión (interrumpiendo el envío) y se retorna ctx.Err()
+func (c *Client) Send(ctx context.Context, channel Channel, content io.Reader, name string) error {
+	return c.send(ctx, channel, content, name, 0)
+}
+
+//Función que envía un archivo con los flags del formato extendido indicados además de los que se negocian con el
+//servidor (por ejemplo, FLAG_RELATIVE_PATH para los archivos de un directorio)
+func (c *Client) send(ctx context.Context, channel Channel, content io.Reader, name string, extraFlags byte) error {
+	var filename []byte = []byte(name)
+	//Si el canal tiene un alias numérico, se envía al número
+	channel = c.resolveChannel(channel)
+	//Se revisa la longitud del nombre del archivo
+	if len(filename) == 0 {
+		return newError(STATUS_USAGE, "", "File name can't be empty", nil)
+	}
+	if len(filename) > LONG_FILENAME_MAX_LENGTH {
+		return newError(STATUS_USAGE, "", fmt.Sprint("File name is too long (max length including file extension: ", LONG_FILENAME_MAX_LENGTH, " bytes)"), nil)
+	}
+	file, seekable := content.(io.ReadSeeker)
+	if seekable {
+		_, seekError := file.Seek(0, io.SeekCurrent)
+		seekable = seekError == nil
+	}
+	if !seekable {
+		chunkedError := c.sendChunked(ctx, channel, content, filename, extraFlags)
+		//Si se canceló el envío, el error se debe al cierre de la conexión
+		if chunkedError != nil && ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if chunkedError != errChunkedUnsupported {
+			return chunkedError
+		}
+		spoolFile, spoolError := spoolContent(content)
+		if spoolError != nil {
+			return newError(STATUS_FILESYSTEM, "", "Error while buffering file contents", spoolError)
+		}
+		defer os.Remove(spoolFile.Name())
+		defer spoolFile.Close()
+		file = spoolFile
+	}
+	//Obtener el tamaño del archivo
+	fileSize, sizeError := file.Seek(0, io.SeekEnd)
+	//Error check
+	if sizeError != nil {
+		return newError(STATUS_FILESYSTEM, "", "Error while getting file size", sizeError)
+	}
+	//Identificador de la transferencia, con el que se puede reanudar en otra conexión
+	var transferID []byte = make([]byte, TRANSFER_ID_LENGTH)
+	_, randomError := rand.Read(transferID)
+	if randomError != nil {
+		return newError(STATUS_PROTOCOL, "", "Error while generating transfer ID", randomError)
+	}
+	//Los archivos regulares se envían con sus metadatos (si el servidor los soporta)
+	var metadata *FileMetadata = readFileMetadata(content)
+	var offset int64 = 0
+	var resumable bool = false
+	for attempt := 0; ; attempt++ {
+		attemptResumable, failure := c.sendAttempt(ctx, channel, filename, extraFlags, metadata, file, fileSize, transferID, offset)
+		if attemptResumable {
+			resumable = true
+		}
+		if failure == nil {
+			return nil
+		}
+		//Si se canceló el envío, el error se debe al cierre de la conexión
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		//Solo se reintenta si la conexión falló y el archivo se envió como reanudable (el contenido cifrado no lo es), o
+		//si el servidor pidió continuar desde otro byte
+		if ((!failure.network || !resumable) && failure.offset < 0) || attempt >= c.Retries {
+			return failure.err
+		}
+		c.logf("WARNING: %v (retrying %d/%d)\n", failure.err.Error(), attempt+1, c.Retries)
+		offset = failure.offset
+		if offset < 0 {
+			//Esperar antes de reconectar y consultar al servidor el último byte que recibió
+			select {
+			case <-time.After(time.Duration(attempt+1) * RETRY_DELAY):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			var queryError error
+			offset, queryError = c.queryResumeOffset(ctx, channel, transferID)
+			if queryError != nil {
+				c.logln("WARNING: Could not get resume offset from server (" + queryError.Error() + "), resending whole file")
+				offset = 0
+			}
+		}
+		if offset > fileSize {
+			offset = 0
+		}
+	}
+}
+
+//Función que copia un contenido sin posibilidad de búsqueda a un archivo temporal
+func spoolContent(content io.Reader) (*os.File, error) {
+	file, fileError := os.CreateTemp("", "filesharing-*"+PARTIAL_FILE_SUFFIX)
+	if fileError != nil {
+		return nil, fileError
+	}
+	_, copyError := io.Copy(file, content)
+	if copyError != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return nil, copyError
+	}
+	return file, nil
+}
+
+//Función que realiza un intento de envío del archivo al servidor a partir del byte indicado (cifrando el contenido si se
+//configuró una frase secreta). Retorna si el archivo se envió como reanudable (el servidor soporta la reanudación y el
+//contenido no se cifra) y, si el envío no se completó, el motivo
+func (c *Client) sendAttempt(ctx context.Context, channel Channel, filename []byte, extraFlags byte, metadata *FileMetadata, file io.ReadSeeker, fileSize int64, transferID []byte, offset int64) (bool, *sendFailure) {
+	//Iniciar conexión con el servidor para enviar el mensaje y el archivo (negociando las capacidades a utilizar)
+	c.logln("Connecting to server...")
+	var connection net.Conn
+	var capabilities uint32
+	var connectionError error
+	connection, capabilities, connectionError = c.connectToServer(ctx, true)
+	//Error check
+	if connectionError != nil {
+		return false, newSendFailure(STATUS_COMMUNICATION, "Error while connecting to server", connectionError, true)
+	}
+	c.logln("Connection successful")
+	//Asegurarse de que la conexión se cierre (también si se cancela el envío)
+	defer connection.Close()
+	var stopWatching func() = closeOnCancel(ctx, connection)
+	defer stopWatching()
+
+	//Se crea la cabecera del mensaje que se enviará al servidor (comando, canal). Si el servidor lo soporta, se utiliza
+	//el formato extendido; de lo contrario el nombre debe caber en el campo de longitud fija del comando send
+	var command protocol.Command = protocol.SEND_COMMAND
+	var sendFlags byte = 0
+	if capabilities&CAP_LONG_FILENAMES != 0 {
+		command = protocol.EXTENDED_SEND_COMMAND
+		sendFlags = extraFlags
+		//Si el servidor lo soporta, se envía el hash del archivo al final del contenido para verificar su integridad
+		if capabilities&CAP_CHECKSUM != 0 {
+			sendFlags |= FLAG_SHA256_TRAILER
+		}
+		//Si el servidor lo soporta, se envía el identificador de la transferencia para poder reanudarla
+		if capabilities&CAP_RESUME != 0 {
+			sendFlags |= FLAG_RESUMABLE
+		}
+		//Si el servidor lo soporta, se envían los metadatos del archivo
+		if capabilities&CAP_METADATA != 0 && metadata != nil {
+			sendFlags |= FLAG_METADATA
+		}
+	} else if extraFlags != 0 {
+		//Los archivos de un directorio (y su manifiesto) solo pueden enviarse con el formato extendido
+		return false, newSendFailure(STATUS_USAGE, "Server does not support directory transfers", nil, false)
+	} else if len(filename) > FILENAME_MAX_LENGTH {
+		return false, newSendFailure(STATUS_USAGE, fmt.Sprint("File name is too long for this server (max length including file extension: ", FILENAME_MAX_LENGTH, " bytes)"), nil, false)
+	}
+	supportError := checkChannelSupport(channel, capabilities)
+	if supportError != nil {
+		return false, newSendFailure(STATUS_USAGE, "", supportError, false)
+	}
+	//El contenido cifrado no se puede reanudar, pues cada intento se cifra con una llave distinta. Tampoco se envía el
+	//hash del archivo: el cifrado ya autentica el contenido, y el hash del texto plano lo revelaría al servidor
+	if c.Passphrase != nil {
+		if capabilities&CAP_LONG_FILENAMES == 0 || capabilities&CAP_ENCRYPTION == 0 {
+			return false, newSendFailure(STATUS_USAGE, "Server does not support end-to-end encrypted transfers", nil, false)
+		}
+		sendFlags = (sendFlags | FLAG_ENCRYPTED) &^ (FLAG_RESUMABLE | FLAG_SHA256_TRAILER)
+	}
+	var resumable bool = sendFlags&FLAG_RESUMABLE != 0
+	if !resumable {
+		offset = 0
+	}
+	//Crear el campo con el nombre del archivo según el formato del mensaje (comando)
+	var filenameField []byte = createFilenameField(command, sendFlags, filename)
+	//Añadir los metadatos del archivo. Si se cifra el contenido, se autentican junto con los flags y el nombre (para que
+	//no puedan alterarse en el camino) y se omite el tipo de contenido, pues el bloque viaja en claro y revelaría al
+	//servidor de qué se trata el archivo
+	var authenticatedData []byte = append([]byte{sendFlags}, filename...)
+	if sendFlags&FLAG_METADATA != 0 {
+		if sendFlags&FLAG_ENCRYPTED != 0 {
+			var plainMetadata FileMetadata = *metadata
+			plainMetadata.ContentType = ""
+			metadata = &plainMetadata
+		}
+		var metadataBlock []byte = createMetadataBlock(metadata)
+		filenameField = append(filenameField, metadataBlock...)
+		authenticatedData = append(authenticatedData, metadataBlock...)
+	}
+	//Añadir los datos de reanudación (identificador, byte inicial y tamaño total del archivo)
+	if sendFlags&FLAG_RESUMABLE != 0 {
+		filenameField = append(filenameField, createResumeBlock(transferID, offset, fileSize)...)
+	}
+	//Si se cifra el contenido, se añaden los datos de cifrado (sal y prefijo de los nonces)
+	var contentWriter io.Writer = connection
+	var encrypter *encryptingWriter
+	var contentSize int64 = fileSize - offset
+	if sendFlags&FLAG_ENCRYPTED != 0 {
+		var encryptionBlock []byte
+		var encryptionError error
+		encrypter, encryptionBlock, encryptionError = newEncryptingWriter(connection, c.Passphrase, channel, authenticatedData)
+		if encryptionError != nil {
+			return resumable, newSendFailure(STATUS_PROTOCOL, "Error while preparing file encryption", encryptionError, false)
+		}
+		filenameField = append(filenameField, encryptionBlock...)
+		contentWriter = encrypter
+		contentSize = encryptedSize(fileSize)
+	}
+	//En los canales con nombre, el nombre del canal se antepone al campo del nombre del archivo
+	filenameField = append(createChannelNameField(channel), filenameField...)
+	//Calcular la longitud del contendido (nombre + contenido del archivo + hash)
+	var contentLength int64 = int64(len(filenameField)) + contentSize
+	if sendFlags&FLAG_SHA256_TRAILER != 0 {
+		contentLength += sha256.Size
+	}
+
+	//El hash del archivo se calcula conforme se envía (sin volver a leerlo). Al reanudar, se calcula primero el de la
+	//parte que ya fue recibida
+	var fileHash hash.Hash = sha256.New()
+	_, seekError := file.Seek(0, io.SeekStart)
+	if seekError == nil && offset > 0 {
+		c.logf("Resuming transfer from byte %d...\n", offset)
+		_, seekError = io.CopyN(fileHash, file, offset)
+	}
+	if seekError != nil {
+		return resumable, newSendFailure(STATUS_FILESYSTEM, "Error while reading file contents", seekError, false)
+	}
+
+	//Enviar el header y el nombre del archivo (el archivo se enviará iterativamente luego)
+	var messageError error = protocol.NewEncoder(connection).EncodeHeader(protocol.Header{Command: command, Channel: channel.headerChannel(), Length: contentLength})
+	if messageError == nil {
+		_, messageError = connection.Write(filenameField)
+	}
+	//Error check
+	if messageError != nil {
+		return resumable, newSendFailure(STATUS_COMMUNICATION, "Error while sending message to server", messageError, true)
+	}
+	//Enviar el archivo de forma iterativa (con un buffer temporal)
+	c.logf("Sending %d bytes...\n", fileSize-offset)
+	var tempBuffer []byte = make([]byte, BUFFER_SIZE)
+	var sentLength int64 = 0
+	for {
+		//Leer del archivo al buffer temporal
+		readBytes, readError := file.Read(tempBuffer)
+		if readError != nil {
+			if readError == io.EOF {
+				c.logf("File read completely (sent %d bytes)\n", sentLength)
+				break
+			}
+			return resumable, newSendFailure(STATUS_COMMUNICATION, "Error while reading file contents", readError, false)
+		}
+		fileHash.Write(tempBuffer[:readBytes])
+		//Enviar el buffer al cliente
+		sentBytes, sendError := contentWriter.Write(tempBuffer[:readBytes])
+		if sendError != nil {
+			return resumable, newSendFailure(STATUS_COMMUNICATION, "Error while sending file contents", sendError, true)
+		}
+		//Actualizar la cantidad enviada
+		sentLength += int64(sentBytes)
+		//Comprobar que lo que se lee se esté enviando completamente
+		if readBytes != sentBytes {
+			return resumable, newSendFailure(STATUS_COMMUNICATION, "File buffer was sent incompletely", nil, true)
+		}
+	}
+	//Asegurarse de que el archivo se leyó y envió completamente
+	if sentLength != fileSize-offset {
+		return resumable, newSendFailure(STATUS_COMMUNICATION, "File was sent incompletely", nil, false)
+	}
+	//Cifrar y enviar el último bloque del contenido
+	if encrypter != nil {
+		closeError := encrypter.Close()
+		if closeError != nil {
+			return resumable, newSendFailure(STATUS_COMMUNICATION, "Error while sending file contents", closeError, true)
+		}
+	}
+	//Enviar el hash del archivo al final del contenido
+	if sendFlags&FLAG_SHA256_TRAILER != 0 {
+		_, sendError := connection.Write(fileHash.Sum(nil))
+		if sendError != nil {
+			return resumable, newSendFailure(STATUS_COMMUNICATION, "Error while sending file checksum", sendError, true)
+		}
+	}
+	//Obtener respuesta del servidor (empezando por el header)
+	c.logln("File sent. Awaiting server response...")
+	response, responseError := protocol.NewDecoder(connection).Decode(BUFFER_SIZE)
+	//Error check
+	if responseError == protocol.ErrContentTooLong || responseError == protocol.ErrInvalidLength {
+		return resumable, newSendFailure(STATUS_COMMUNICATION, "Server's response is too long", nil, false)
+	} else if responseError != nil {
+		return resumable, newSendFailure(STATUS_COMMUNICATION, "Error while getting server's response", responseError, true)
+	}
+
+	//Interpretar respuesta
+	switch response.Command {
+	case protocol.OK_COMMAND:
+		c.logln("Server received file successfully. It will be sent to all subscribed clients on selected channel.")
+	case protocol.ERROR_COMMAND:
+		return resumable, newSendFailure(STATUS_COMMUNICATION, "", &ServerError{string(response.Content)}, false)
+	case protocol.RESUME_OFFSET_COMMAND:
+		//El servidor tiene una cantidad distinta de bytes de la transferencia y pide continuar desde otro byte
+		resumeOffset, offsetError := parseResumeOffset(response.Content)
+		if offsetError == nil && !resumable {
+			offsetError = errors.New("transfer is not resumable")
+		}
+		if offsetError != nil {
+			return resumable, newSendFailure(STATUS_COMMUNICATION, "Invalid resume request received from server", offsetError, false)
+		}
+		var failure *sendFailure = newSendFailure(STATUS_COMMUNICATION, fmt.Sprintf("Server requested to resume transfer from byte %d", resumeOffset), nil, true)
+		failure.offset = resumeOffset
+		return resumable, failure
+	default:
+		return resumable, newSendFailure(STATUS_COMMUNICATION, fmt.Sprint("Invalid command received from server: ", response.Command), nil, false)
+	}
+	return resumable, nil
+}
@@ -0,0 +1,403 @@
+package client
+
+//Archivo que contiene las funciones para suscribirse a un canal y cancelar la suscripción
+
+import (
+	"Client/protocol"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+//Suscripción activa a un canal
+type subscription struct {
+	channels     []Channel    //Canales suscritos, tal como se indicaron (en el modo push, uno solo)
+	address      []byte       //Dirección anunciada al servidor (nil en el modo push)
+	listener     net.Listener //Listener que recibe las conexiones del servidor (nil en el modo push)
+	session      *pushSession //Conexión de suscripción del modo push (nil si se utiliza un listener)
+	stopOnce     sync.Once
+	stopError    error          //Resultado de cancelar la suscripción
+	stopped      chan struct{}  //Se cierra una vez cancelada la suscripción
+	transfers    sync.WaitGroup //Transferencias en curso (Subscribe espera a que terminen antes de retornar)
+	active       int32          //Cantidad de transferencias en curso
+	abortedMutex sync.Mutex
+	aborted      []string //Archivos cuya transferencia se interrumpió al terminar la suscripción
+}
+
+//Función para suscribirse a un canal y recibir los archivos que se envíen a él, entregándolos al handler. Bloquea
+//hasta que se cancele la suscripción (con Unsubscribe o cancelando el contexto, en cuyo caso se retorna ctx.Err()) o
+//falle la comunicación con el servidor. Una vez que deja de recibir transferencias nuevas, espera hasta GracePeriod a
+//que terminen las que están en curso, e interrumpe las restantes (los handlers descartan lo recibido); en ese caso
+//retorna un *AbortedTransfersError con los archivos interrumpidos
+func (c *Client) Subscribe(ctx context.Context, channel Channel, handler Handler) error {
+	return c.SubscribeChannels(ctx, []Channel{channel}, handler)
+}
+
+//Función para suscribirse a varios canales con un mismo listener, igual que Subscribe. Cada archivo se recibe en el
+//canal indicado en su mensaje (IncomingFile.Channel). Si falla la suscripción a alguno de los canales, se cancela la de
+//los anteriores. El modo push solo admite un canal por suscripción
+func (c *Client) SubscribeChannels(ctx context.Context, channels []Channel, handler Handler) error {
+	if len(channels) == 0 {
+		return newError(STATUS_USAGE, "", "No channels to subscribe to", nil)
+	}
+	//Los canales se suscriben con su alias numérico si lo tienen (un canal y su alias son el mismo canal)
+	var resolvedChannels []Channel
+	for _, channel := range channels {
+		var resolvedChannel Channel = c.resolveChannel(channel)
+		if containsChannel(resolvedChannels, resolvedChannel) {
+			return newError(STATUS_USAGE, "", fmt.Sprint("Channel ", channel, " is listed more than once"), nil)
+		}
+		if c.isSubscribed(channel) {
+			return newError(STATUS_USAGE, "", "", fmt.Errorf("%w %v", ErrAlreadySubscribed, channel))
+		}
+		resolvedChannels = append(resolvedChannels, resolvedChannel)
+	}
+	//En el modo push los archivos se reciben por la conexión de suscripción
+	if c.Push {
+		if len(channels) > 1 {
+			return newError(STATUS_USAGE, "", "Push mode supports a single channel per subscription", nil)
+		}
+		return c.subscribeWithPush(ctx, channels[0], handler)
+	}
+	//Se crea un listener del cliente para poder recibir mensajes del servidor cuando un archivo sea enviado
+	var listener net.Listener
+	var listenerError error
+	listener, listenerError = c.listen()
+	//Error check
+	if listenerError != nil {
+		return newError(STATUS_COMMUNICATION, "", "Error while starting client listener for subscription", listenerError)
+	}
+	defer listener.Close()
+
+	//Se obtiene en un string la IP y puerto que se anuncian al servidor para conectarse al listener del cliente
+	clientAddress, addressError := advertisedAddress(listener.Addr(), c.Advertise)
+	if addressError != nil {
+		return newError(STATUS_USAGE, "", "", addressError)
+	}
+	var addressBuffer []byte = []byte(clientAddress)
+	//Se suscribe la dirección a cada canal. Si falla alguno, se cancelan las suscripciones ya realizadas
+	for i, channel := range resolvedChannels {
+		subscribeError := c.subscribeAddress(ctx, channel, addressBuffer)
+		if subscribeError != nil {
+			for _, subscribedChannel := range resolvedChannels[:i] {
+				c.unsubscribe(context.Background(), subscribedChannel, addressBuffer)
+			}
+			return subscribeError
+		}
+	}
+	if clientAddress != listener.Addr().String() {
+		c.logln("Awaiting incoming file transfers on " + listener.Addr().String() + " (advertised as " + clientAddress + ")...")
+	} else {
+		c.logln("Awaiting incoming file transfers on " + clientAddress + "...")
+	}
+
+	//Una vez exitosa la suscripción, el cliente queda esperando transferencias de archivos mediante el listener
+	var sub *subscription = &subscription{channels: append([]Channel{}, channels...), address: addressBuffer, listener: listener, stopped: make(chan struct{})}
+	registerError := c.registerSubscription(sub)
+	if registerError != nil {
+		for _, channel := range resolvedChannels {
+			c.unsubscribe(context.Background(), channel, addressBuffer)
+		}
+		return registerError
+	}
+	defer c.unregisterSubscription(sub)
+	//Las transferencias no se interrumpen al cancelar el contexto, sino al terminar el periodo de gracia
+	transfersCtx, cancelTransfers := context.WithCancel(context.Background())
+	defer cancelTransfers()
+	c.stopOnCancel(ctx, sub)
+	//Ahora se atienden las transferencias
+	for {
+		var incomingConnection net.Conn
+		var incomingConnError error
+		//Aceptar conexión
+		incomingConnection, incomingConnError = listener.Accept()
+		//Error check
+		if incomingConnError != nil {
+			//Al cancelar la suscripción se cierra el listener
+			var result error
+			select {
+			case <-sub.stopped:
+				result = sub.stopError
+				if result == nil {
+					result = ctx.Err()
+				}
+			default:
+				result = newError(STATUS_PROTOCOL, "", "Error while accepting incoming connection", incomingConnError)
+			}
+			return c.drainTransfers(sub, cancelTransfers, result)
+		}
+
+		//Recibir el archivo y entregarlo al handler
+		c.startTransfer(transfersCtx, sub, incomingConnection, handler)
+	}
+}
+
+//Función que recibe en una goroutine un archivo de una suscripción, registrando su nombre si la transferencia se
+//interrumpe
+func (c *Client) startTransfer(ctx context.Context, sub *subscription, connection io.ReadWriteCloser, handler Handler) {
+	sub.transfers.Add(1)
+	atomic.AddInt32(&sub.active, 1)
+	go func() {
+		defer sub.transfers.Done()
+		defer atomic.AddInt32(&sub.active, -1)
+		filename, aborted := c.receiveFile(ctx, connection, sub.channels, handler)
+		if aborted {
+			if filename == "" {
+				filename = "(file name not received)"
+			}
+			sub.abortedMutex.Lock()
+			sub.aborted = append(sub.aborted, filename)
+			sub.abortedMutex.Unlock()
+		}
+	}()
+}
+
+//Función que espera a que terminen las transferencias en curso de una suscripción que ya no recibe transferencias
+//nuevas. Las que no terminan dentro del periodo de gracia se interrumpen cancelando su contexto. Retorna el resultado
+//de la suscripción, o un *AbortedTransfersError que lo envuelve si se interrumpió alguna transferencia
+func (c *Client) drainTransfers(sub *subscription, cancelTransfers context.CancelFunc, result error) error {
+	var finished chan struct{} = make(chan struct{})
+	go func() {
+		sub.transfers.Wait()
+		close(finished)
+	}()
+	//Sin periodo de gracia límite, se espera indefinidamente (un canal nil nunca está listo)
+	var gracePeriodEnd <-chan time.Time
+	if c.GracePeriod >= 0 {
+		var timer *time.Timer = time.NewTimer(c.GracePeriod)
+		defer timer.Stop()
+		gracePeriodEnd = timer.C
+	}
+	if active := atomic.LoadInt32(&sub.active); active > 0 && c.GracePeriod != 0 {
+		if c.GracePeriod > 0 {
+			c.logf("Waiting up to %v for %d transfer(s) in progress to finish...\n", c.GracePeriod, active)
+		} else {
+			c.logf("Waiting for %d transfer(s) in progress to finish...\n", active)
+		}
+	}
+	select {
+	case <-finished:
+	case <-gracePeriodEnd:
+		cancelTransfers()
+		<-finished
+	}
+	sub.abortedMutex.Lock()
+	defer sub.abortedMutex.Unlock()
+	if len(sub.aborted) == 0 {
+		return result
+	}
+	return &AbortedTransfersError{Files: sub.aborted, Err: result}
+}
+
+//Función que suscribe al canal indicado (ya resuelto) la dirección en la que el servidor entrega los archivos
+func (c *Client) subscribeAddress(ctx context.Context, channel Channel, address []byte) error {
+	//El cliente se comunica con el servidor para suscribirse al canal (enviando un mensaje con la dirección)
+	var message protocol.Message = createChannelMessage(protocol.SUBSCRIBE_COMMAND, channel, address)
+
+	//Ahora es posible enviar el mensaje al servidor
+	c.logln("Sending subscription request to server...")
+	//Se entabla la conexión con el servidor
+	var connection net.Conn
+	var capabilities uint32
+	var connectionError error
+	connection, capabilities, connectionError = c.connectToServer(ctx, false)
+	//Error check
+	if connectionError != nil {
+		return newError(STATUS_COMMUNICATION, "", "Error while connecting to server", connectionError)
+	}
+	supportError := checkChannelSupport(channel, capabilities)
+	if supportError != nil {
+		connection.Close()
+		return supportError
+	}
+	//Se envía el mensaje
+	err := protocol.NewEncoder(connection).Encode(message)
+	//Error check
+	if err != nil {
+		connection.Close()
+		return newError(STATUS_COMMUNICATION, "", "Error while sending message to server", err)
+	}
+
+	c.logln("Request sent. Awaiting server response...")
+	//Recibir respuesta del servidor
+	response, responseError := readServerResponse(connection)
+	//Cerrar conexión, pues ya se obtuvo una respuesta
+	connection.Close()
+	//Error check
+	if responseError != nil {
+		return responseError
+	}
+
+	//Interpretar respuesta
+	switch response.Command {
+	case protocol.OK_COMMAND:
+		c.logln("Client successfully subscribed to channel", channel)
+	case protocol.ERROR_COMMAND:
+		return newError(STATUS_COMMUNICATION, "", "", &ServerError{string(response.Content)})
+	default:
+		return newError(STATUS_COMMUNICATION, "", fmt.Sprint("Invalid command received from server: ", response.Command), nil)
+	}
+	return nil
+}
+
+//Función para cancelar la suscripción a un canal. La llamada a Subscribe correspondiente retorna una vez cancelada. Si
+//la suscripción incluye varios canales (ver SubscribeChannels), se cancela la de todos ellos
+func (c *Client) Unsubscribe(ctx context.Context, channel Channel) error {
+	c.subscriptionsMutex.Lock()
+	var sub *subscription = c.subscriptions[c.resolveChannel(channel)]
+	c.subscriptionsMutex.Unlock()
+	if sub == nil {
+		return newError(STATUS_USAGE, "", "", fmt.Errorf("%w %v", ErrNotSubscribed, channel))
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return c.stopSubscription(ctx, sub)
+}
+
+//Función que indica si hay una suscripción activa al canal indicado
+func (c *Client) isSubscribed(channel Channel) bool {
+	c.subscriptionsMutex.Lock()
+	defer c.subscriptionsMutex.Unlock()
+	return c.subscriptions[c.resolveChannel(channel)] != nil
+}
+
+//Función que registra una suscripción activa en todos sus canales (ya resueltos). Solo puede haber una suscripción por
+//canal
+func (c *Client) registerSubscription(sub *subscription) error {
+	c.subscriptionsMutex.Lock()
+	defer c.subscriptionsMutex.Unlock()
+	for _, channel := range sub.channels {
+		if c.subscriptions[c.resolveChannel(channel)] != nil {
+			return newError(STATUS_USAGE, "", "", fmt.Errorf("%w %v", ErrAlreadySubscribed, channel))
+		}
+	}
+	if c.subscriptions == nil {
+		c.subscriptions = map[Channel]*subscription{}
+	}
+	for _, channel := range sub.channels {
+		c.subscriptions[c.resolveChannel(channel)] = sub
+	}
+	return nil
+}
+
+//Función que elimina el registro de una suscripción que ya terminó
+func (c *Client) unregisterSubscription(sub *subscription) {
+	c.subscriptionsMutex.Lock()
+	defer c.subscriptionsMutex.Unlock()
+	for _, channel := range sub.channels {
+		if c.subscriptions[c.resolveChannel(channel)] == sub {
+			delete(c.subscriptions, c.resolveChannel(channel))
+		}
+	}
+}
+
+//Función que cancela la suscripción si se cancela el contexto de Subscribe. La solicitud al servidor no utiliza ese
+//contexto (que ya está cancelado), para que se complete
+func (c *Client) stopOnCancel(ctx context.Context, sub *subscription) {
+	go func() {
+		select {
+		case <-ctx.Done():
+			c.stopSubscription(context.Background(), sub)
+		case <-sub.stopped:
+		}
+	}()
+}
+
+//Función que cancela una suscripción (una única vez) y detiene la recepción de archivos
+func (c *Client) stopSubscription(ctx context.Context, sub *subscription) error {
+	sub.stopOnce.Do(func() {
+		if sub.session != nil {
+			sub.stopError = sub.session.unsubscribe()
+		} else {
+			//Se cancela la suscripción de todos los canales aunque falle alguno
+			for _, channel := range sub.channels {
+				unsubscribeError := c.unsubscribe(ctx, c.resolveChannel(channel), sub.address)
+				if sub.stopError == nil {
+					sub.stopError = unsubscribeError
+				}
+			}
+		}
+		//Se indica que terminó la suscripción antes de cerrar el listener, para que el error de este cierre no se confunda
+		//con una falla. En el modo push, la conexión de suscripción se cierra una vez que terminan las transferencias en
+		//curso, pues estas se reciben por ella (ver subscribeWithPush)
+		close(sub.stopped)
+		if sub.session == nil {
+			sub.listener.Close()
+		}
+	})
+	return sub.stopError
+}
+
+//Función que determina la dirección que se anuncia al servidor a partir de la del listener y la configurada en
+//Advertise (si no tiene puerto, se usa el del listener)
+func advertisedAddress(listenerAddress net.Addr, advertise string) (string, error) {
+	listenerHost, listenerPort, splitError := net.SplitHostPort(listenerAddress.String())
+	if splitError != nil {
+		return "", splitError
+	}
+	if advertise == "" {
+		//El servidor no puede conectarse a una dirección no especificada (0.0.0.0 o ::)
+		if ip := net.ParseIP(listenerHost); ip != nil && ip.IsUnspecified() {
+			return "", errors.New("listener on all interfaces (" + listenerAddress.String() + ") requires an advertised address reachable by the server")
+		}
+		return listenerAddress.String(), nil
+	}
+	advertiseHost, advertisePort, splitError := net.SplitHostPort(advertise)
+	if splitError != nil {
+		//Sin puerto: se utiliza el del listener
+		advertiseHost = strings.TrimSuffix(strings.TrimPrefix(advertise, "["), "]")
+	}
+	if advertisePort == "" {
+		advertisePort = listenerPort
+	}
+	return net.JoinHostPort(advertiseHost, advertisePort), nil
+}
+
+//Función que envía al servidor la solicitud de cancelar la suscripción de la dirección indicada a un canal (ya resuelto)
+func (c *Client) unsubscribe(ctx context.Context, channel Channel, address []byte) error {
+	//Anunciar que el cliente va a cancelar su suscripción al canal
+	c.logf("\nCancelling subscription of %v to channel %v...\n", string(address), channel)
+	//Armar el mensaje a enviar al servidor
+	var message protocol.Message = createChannelMessage(protocol.UNSUBSCRIBE_COMMAND, channel, address)
+	//Conectarse al servidor para enviar el mensaje
+	connection, capabilities, connError := c.connectToServer(ctx, false)
+	if connError != nil {
+		return newError(STATUS_COMMUNICATION, "", "Error while connecting to server", connError)
+	}
+	//Asegurarse de cerrar la conexión al salir
+	defer connection.Close()
+	supportError := checkChannelSupport(channel, capabilities)
+	if supportError != nil {
+		return supportError
+	}
+	//Enviar mensaje
+	sendError := protocol.NewEncoder(connection).Encode(message)
+	if sendError != nil {
+		return newError(STATUS_COMMUNICATION, "", "Error while sending message to server", sendError)
+	}
+	c.logln("Request sent. Awaiting server response...")
+	//Recibir respuesta del servidor
+	response, responseError := readServerResponse(connection)
+	//Error check
+	if responseError != nil {
+		return responseError
+	}
+
+	//Interpretar respuesta
+	switch response.Command {
+	case protocol.OK_COMMAND:
+		c.logln("Client successfully unsubscribed from channel", channel)
+	case protocol.ERROR_COMMAND:
+		return newError(STATUS_COMMUNICATION, "", "", &ServerError{string(response.Content)})
+	default:
+		return newError(STATUS_COMMUNICATION, "", fmt.Sprint("Invalid command received from server: ", response.Command), nil)
+	}
+	return nil
+}
@@ -0,0 +1,178 @@
+package client
+
+//Archivo con los tiempos máximos de espera de las conexiones: al conectarse al servidor, sin recibir datos, sin poder
+//enviar datos y de la transferencia completa
+
+import (
+	"errors"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+//Tiempos máximos de espera por defecto (se utilizan si no se configuran en el Client)
+const DEFAULT_CONNECT_TIMEOUT = 10 * time.Second //Tiempo máximo para establecer una conexión con el servidor
+const DEFAULT_IDLE_TIMEOUT = 30 * time.Second    //Tiempo máximo sin recibir datos o sin poder enviarlos
+
+//Tipos de tiempo máximo de espera
+const TIMEOUT_CONNECT = "connect"   //Al establecer una conexión
+const TIMEOUT_READ = "read"         //Sin recibir datos de la conexión
+const TIMEOUT_WRITE = "write"       //Sin poder enviar datos por la conexión
+const TIMEOUT_TRANSFER = "transfer" //Duración total de la conexión
+
+//Error que indica que se superó un tiempo máximo de espera
+type TimeoutError struct {
+	Kind  string        //Tipo de tiempo máximo superado (TIMEOUT_CONNECT, TIMEOUT_READ, TIMEOUT_WRITE o TIMEOUT_TRANSFER)
+	Limit time.Duration //Tiempo máximo configurado
+}
+
+func (e *TimeoutError) Error() string {
+	switch e.Kind {
+	case TIMEOUT_CONNECT:
+		return "connection timed out after " + e.Limit.String()
+	case TIMEOUT_READ:
+		return "no data received for " + e.Limit.String()
+	case TIMEOUT_WRITE:
+		return "could not send data for " + e.Limit.String()
+	default:
+		return "transfer did not complete within " + e.Limit.String()
+	}
+}
+
+func (e *TimeoutError) Timeout() bool {
+	return true
+}
+
+func (e *TimeoutError) Temporary() bool {
+	return false
+}
+
+func (e *TimeoutError) Unwrap() error {
+	return os.ErrDeadlineExceeded
+}
+
+//Función que obtiene un tiempo máximo configurado: 0 corresponde al valor por defecto y un valor negativo lo desactiva
+//(retorna 0)
+func timeoutOrDefault(timeout time.Duration, defaultTimeout time.Duration) time.Duration {
+	if timeout == 0 {
+		return defaultTimeout
+	}
+	if timeout < 0 {
+		return 0
+	}
+	return timeout
+}
+
+//Conexión que aplica los tiempos máximos de espera del cliente a cada lectura y escritura. Los plazos fijados con
+//SetReadDeadline y SetWriteDeadline (por ejemplo, el del saludo) reemplazan al tiempo máximo sin recibir o enviar datos
+//mientras estén fijados; el plazo de la transferencia completa se aplica siempre
+type timeoutConn struct {
+	net.Conn
+	readTimeout      time.Duration
+	writeTimeout     time.Duration
+	transferTimeout  time.Duration
+	transferDeadline time.Time //Plazo de la transferencia completa (cero si no hay)
+	deadlinesMutex   sync.Mutex
+	readDeadline     time.Time //Plazo de lectura fijado explícitamente (cero si no hay)
+	writeDeadline    time.Time //Plazo de escritura fijado explícitamente (cero si no hay)
+}
+
+//Función que envuelve una conexión con los tiempos máximos de espera del cliente. El tiempo máximo de la transferencia
+//completa solo se aplica a las conexiones por las que se transfiere un archivo
+func (c *Client) withTimeouts(connection net.Conn, transfer bool) *timeoutConn {
+	var wrapped *timeoutConn = &timeoutConn{
+		Conn:            connection,
+		readTimeout:     timeoutOrDefault(c.ReadTimeout, DEFAULT_IDLE_TIMEOUT),
+		writeTimeout:    timeoutOrDefault(c.WriteTimeout, DEFAULT_IDLE_TIMEOUT),
+		transferTimeout: timeoutOrDefault(c.TransferTimeout, 0),
+	}
+	if transfer && wrapped.transferTimeout > 0 {
+		wrapped.transferDeadline = time.Now().Add(wrapped.transferTimeout)
+	}
+	return wrapped
+}
+
+//Función que determina el plazo de la siguiente operación y el tipo de tiempo máximo que lo define (vacío si el plazo
+//fue fijado explícitamente)
+func (c *timeoutConn) nextDeadline(explicit *time.Time, idle time.Duration, idleKind string) (time.Time, string) {
+	c.deadlinesMutex.Lock()
+	var deadline time.Time = *explicit
+	c.deadlinesMutex.Unlock()
+	var kind string = ""
+	if deadline.IsZero() && idle > 0 {
+		deadline = time.Now().Add(idle)
+		kind = idleKind
+	}
+	if !c.transferDeadline.IsZero() && (deadline.IsZero() || c.transferDeadline.Before(deadline)) {
+		deadline = c.transferDeadline
+		kind = TIMEOUT_TRANSFER
+	}
+	return deadline, kind
+}
+
+//Función que reemplaza el error de un plazo vencido por el error del tiempo máximo correspondiente
+func (c *timeoutConn) timeoutError(err error, kind string, idle time.Duration) error {
+	if kind == "" || !errors.Is(err, os.ErrDeadlineExceeded) {
+		return err
+	}
+	if kind == TIMEOUT_TRANSFER {
+		return &TimeoutError{Kind: kind, Limit: c.transferTimeout}
+	}
+	return &TimeoutError{Kind: kind, Limit: idle}
+}
+
+func (c *timeoutConn) Read(p []byte) (int, error) {
+	deadline, kind := c.nextDeadline(&c.readDeadline, c.readTimeout, TIMEOUT_READ)
+	c.Conn.SetReadDeadline(deadline)
+	n, readError := c.Conn.Read(p)
+	return n, c.timeoutError(readError, kind, c.readTimeout)
+}
+
+func (c *timeoutConn) Write(p []byte) (int, error) {
+	deadline, kind := c.nextDeadline(&c.writeDeadline, c.writeTimeout, TIMEOUT_WRITE)
+	c.Conn.SetWriteDeadline(deadline)
+	n, writeError := c.Conn.Write(p)
+	return n, c.timeoutError(writeError, kind, c.writeTimeout)
+}
+
+//Los plazos fijados explícitamente también se aplican de inmediato, para interrumpir una operación en curso
+func (c *timeoutConn) SetDeadline(t time.Time) error {
+	c.SetReadDeadline(t)
+	return c.SetWriteDeadline(t)
+}
+
+func (c *timeoutConn) SetReadDeadline(t time.Time) error {
+	c.deadlinesMutex.Lock()
+	c.readDeadline = t
+	c.deadlinesMutex.Unlock()
+	if t.IsZero() {
+		return nil
+	}
+	return c.Conn.SetReadDeadline(t)
+}
+
+func (c *timeoutConn) SetWriteDeadline(t time.Time) error {
+	c.deadlinesMutex.Lock()
+	c.writeDeadline = t
+	c.deadlinesMutex.Unlock()
+	if t.IsZero() {
+		return nil
+	}
+	return c.Conn.SetWriteDeadline(t)
+}
+
+//Listener que aplica los tiempos máximos de espera del cliente a las conexiones aceptadas (por cada una se recibe un
+//archivo)
+type timeoutListener struct {
+	net.Listener
+	client *Client
+}
+
+func (l *timeoutListener) Accept() (net.Conn, error) {
+	connection, acceptError := l.Listener.Accept()
+	if acceptError != nil {
+		return nil, acceptError
+	}
+	return l.client.withTimeouts(connection, true), nil
+}
@@ -0,0 +1,59 @@
+package client
+
+//Archivo con funciones de apoyo para el procesamiento de mensajes y solicitudes
+
+import (
+	"Client/protocol"
+	"context"
+	"encoding/binary"
+	"io"
+	"strings"
+)
+
+//Función que cierra la conexión si se cancela el contexto (interrumpiendo las lecturas y escrituras en curso). Retorna
+//la función que deja de vigilar el contexto, que debe llamarse al terminar de utilizar la conexión
+func closeOnCancel(ctx context.Context, connection io.Closer) func() {
+	var done chan struct{} = make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			connection.Close()
+		case <-done:
+		}
+	}()
+	return func() {
+		close(done)
+	}
+}
+
+//Función que lee la respuesta del servidor a una solicitud (su contenido no puede superar BUFFER_SIZE)
+func readServerResponse(connection io.Reader) (protocol.Message, error) {
+	response, readError := protocol.NewDecoder(connection).Decode(BUFFER_SIZE)
+	//Error check
+	if readError == protocol.ErrContentTooLong || readError == protocol.ErrInvalidLength {
+		return response, newError(STATUS_COMMUNICATION, "", "Server's response is too long", nil)
+	} else if readError != nil {
+		return response, newError(STATUS_COMMUNICATION, "", "Error while getting server's response", readError)
+	}
+	return response, nil
+}
+
+//Función que crea el campo con el nombre del archivo de un mensaje de envío según su comando: en el formato original
+//(send) el nombre se completa con \x00 hasta FILENAME_MAX_LENGTH; en el extendido se prefija con los flags indicados y su
+//longitud
+func createFilenameField(command protocol.Command, flags byte, filename []byte) []byte {
+	var field []byte
+	if command == protocol.EXTENDED_SEND_COMMAND {
+		var lengthBuffer []byte = make([]byte, 2)
+		binary.LittleEndian.PutUint16(lengthBuffer, uint16(len(filename)))
+		field = append(field, flags)
+		field = append(field, lengthBuffer...)
+		return append(field, filename...)
+	}
+	field = append(field, filename...)
+	//Si el nombre del archivo no ocupaba el tamaño máximo, es necesario llenar los espacios faltantes
+	if FILENAME_MAX_LENGTH > len(filename) {
+		field = append(field, []byte(strings.Repeat("\x00", FILENAME_MAX_LENGTH-len(filename)))...)
+	}
+	return field
+}
@@ -0,0 +1,95 @@
+package client
+
+//Archivo con el handler que escribe los archivos recibidos en un io.Writer (por ejemplo, la salida estándar), ya sea
+//solo su contenido o como tramas con el nombre de cada archivo
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"sync"
+)
+
+//Longitudes especiales de los bloques de las tramas de WriterHandler
+const FRAME_END uint32 = 0              //Fin del archivo (recibido y verificado)
+const FRAME_ABORTED uint32 = 0xFFFFFFFF //Fin de un archivo cuya transferencia falló (su contenido debe descartarse)
+
+//Handler que escribe los archivos recibidos en un io.Writer, de a uno a la vez (las transferencias simultáneas esperan
+//su turno). Sin Framed, solo se escribe el contenido de los archivos. Con Framed, cada archivo se escribe como una
+//trama: la longitud del nombre (2 bytes, little endian), el nombre y el contenido en bloques prefijados por su longitud
+//(4 bytes, little endian), terminando con un bloque de longitud FRAME_END o FRAME_ABORTED
+type WriterHandler struct {
+	Writer io.Writer //Destino de los archivos recibidos
+	Framed bool      //Escribir cada archivo como una trama con su nombre
+
+	writeMutex sync.Mutex
+}
+
+//Función que escribe un archivo recibido en el io.Writer. Si la transferencia falla después de haber escrito parte del
+//contenido, este no puede recuperarse: sin Framed, quien lee el contenido debe revisar el resultado de la transferencia
+func (h *WriterHandler) ReceiveFile(ctx context.Context, incoming *IncomingFile) error {
+	//Los manifiestos de directorios solo se validan, pues no son archivos del directorio
+	if incoming.Manifest {
+		_, manifestError := ReadManifest(incoming)
+		return manifestError
+	}
+	h.writeMutex.Lock()
+	defer h.writeMutex.Unlock()
+	if h.Framed {
+		var nameHeader []byte = make([]byte, 2)
+		binary.LittleEndian.PutUint16(nameHeader, uint16(len(incoming.Name)))
+		writeError := h.write(append(nameHeader, incoming.Name...))
+		if writeError != nil {
+			return writeError
+		}
+	}
+	var tempBuffer []byte = make([]byte, STREAM_CHUNK_SIZE)
+	for {
+		n, readError := incoming.Read(tempBuffer)
+		if n > 0 {
+			writeError := h.writeChunk(tempBuffer[:n])
+			if writeError != nil {
+				return writeError
+			}
+		}
+		//Error check
+		if readError == io.EOF { //Se concluyó la lectura (y se verificó el contenido)
+			return h.endFrame(FRAME_END)
+		} else if readError != nil {
+			h.endFrame(FRAME_ABORTED)
+			//La transferencia se canceló (y se cerró la conexión)
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return readError
+		}
+	}
+}
+
+//Función que escribe un bloque del contenido (prefijado por su longitud si se escriben tramas)
+func (h *WriterHandler) writeChunk(chunk []byte) error {
+	if !h.Framed {
+		return h.write(chunk)
+	}
+	var lengthBuffer []byte = make([]byte, 4, 4+len(chunk))
+	binary.LittleEndian.PutUint32(lengthBuffer, uint32(len(chunk)))
+	return h.write(append(lengthBuffer, chunk...))
+}
+
+//Función que escribe el bloque que termina la trama de un archivo (si se escriben tramas)
+func (h *WriterHandler) endFrame(marker uint32) error {
+	if !h.Framed {
+		return nil
+	}
+	var markerBuffer []byte = make([]byte, 4)
+	binary.LittleEndian.PutUint32(markerBuffer, marker)
+	return h.write(markerBuffer)
+}
+
+func (h *WriterHandler) write(data []byte) error {
+	_, writeError := h.Writer.Write(data)
+	if writeError != nil {
+		return newError(STATUS_FILESYSTEM, "output writing failed", "Error while writing received file to output", writeError)
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package main
+
+//Archivo con las funciones para leer el archivo de configuración del cliente y resolver la dirección del servidor
+
+import (
+	"Client/client"
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+//Constantes de la configuración del cliente
+const SERVER_ENVIRONMENT_VARIABLE = "FILESHARING_SERVER"         //Variable de entorno con la dirección del servidor
+const CONFIG_DIRECTORY = "filesharing"                           //Directorio del cliente dentro del directorio de configuración del usuario
+const CONFIG_FILENAME = "client.conf"                            //Nombre del archivo de configuración por defecto
+const PASSPHRASE_ENVIRONMENT_VARIABLE = "FILESHARING_PASSPHRASE" //Variable de entorno con la frase secreta del canal
+const CHANNEL_ALIAS_PREFIX = "channel."                          //Prefijo de las claves que asocian un número a un canal con nombre
+
+//Dirección (host:puerto) del servidor con el que se comunica el cliente
+var serverAddress string = client.DEFAULT_SERVER_ADDRESS
+
+//Función que obtiene la ruta del archivo de configuración por defecto (vacía si no se puede determinar)
+func defaultConfigPath() string {
+	configDirectory, configError := os.UserConfigDir()
+	if configError != nil {
+		return ""
+	}
+	return filepath.Join(configDirectory, CONFIG_DIRECTORY, CONFIG_FILENAME)
+}
+
+//Función que lee un archivo de configuración con líneas de la forma "clave = valor". Las líneas vacías y las que
+//inician con '#' se ignoran
+func readConfigFile(path string) (map[string]string, error) {
+	file, openError := os.Open(path)
+	if openError != nil {
+		return nil, openError
+	}
+	defer file.Close()
+	var config map[string]string = map[string]string{}
+	var scanner *bufio.Scanner = bufio.NewScanner(file)
+	var lineNumber int = 0
+	for scanner.Scan() {
+		lineNumber++
+		var line string = strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		var parts []string = strings.SplitN(line, "=", 2)
+		if len(parts) != 2 || len(strings.TrimSpace(parts[0])) == 0 {
+			return nil, fmt.Errorf("%v:%d: expected \"key = value\"", path, lineNumber)
+		}
+		config[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	}
+	return config, scanner.Err()
+}
+
+//Función que carga el archivo de configuración indicado o, si no se indicó, el archivo por defecto (si existe)
+func loadConfig(path string) map[string]string {
+	var explicit bool = path != ""
+	if !explicit {
+		path = defaultConfigPath()
+		if path == "" {
+			return map[string]string{}
+		}
+	}
+	config, configError := readConfigFile(path)
+	if configError != nil {
+		if !explicit && os.IsNotExist(configError) {
+			return map[string]string{}
+		}
+		fmt.Println("ERROR: Error while reading configuration file: " + configError.Error())
+		os.Exit(1)
+	}
+	return config
+}
+
+//Función que normaliza la dirección de un servidor: acepta hostnames, direcciones IPv4 e IPv6 (con o sin corchetes) y,
+//si no se indica el puerto, utiliza el puerto por defecto
+func normalizeServerAddress(address string) (string, error) {
+	host, port, splitError := net.SplitHostPort(address)
+	if splitError != nil {
+		//Sin puerto: puede ser un hostname, una IPv4 o una IPv6 (con o sin corchetes)
+		host = strings.TrimSuffix(strings.TrimPrefix(address, "["), "]")
+		port = client.SERVER_PORT
+		if strings.Contains(host, ":") && net.ParseIP(host) == nil {
+			return "", errors.New("invalid address \"" + address + "\"")
+		}
+	}
+	if len(host) == 0 {
+		return "", errors.New("missing host in address \"" + address + "\"")
+	}
+	portNumber, portError := strconv.Atoi(port)
+	if portError != nil || portNumber < 1 || portNumber > 65535 {
+		return "", errors.New("invalid port in address \"" + address + "\"")
+	}
+	return net.JoinHostPort(host, port), nil
+}
+
+//Función que determina la dirección del servidor: el flag tiene prioridad sobre la variable de entorno, y esta sobre
+//el archivo de configuración
+func resolveServerAddress(flagValue string, config map[string]string) {
+	var address string = flagValue
+	var source string = "flag \"-server\""
+	if address == "" {
+		address = os.Getenv(SERVER_ENVIRONMENT_VARIABLE)
+		source = SERVER_ENVIRONMENT_VARIABLE + " environment variable"
+	}
+	if address == "" {
+		address = config["server"]
+		source = "configuration file"
+	}
+	if address == "" {
+		return
+	}
+	normalizedAddress, addressError := normalizeServerAddress(address)
+	if addressError != nil {
+		fmt.Println("ERROR: Invalid server address in " + source + ": " + addressError.Error())
+		os.Exit(1)
+	}
+	serverAddress = normalizedAddress
+}
+
+//Función que obtiene de la configuración los alias numéricos de los canales con nombre (líneas "channel.NOMBRE = NÚMERO")
+func loadChannelAliases(config map[string]string) map[string]int8 {
+	var aliases map[string]int8 = map[string]int8{}
+	for key, value := range config {
+		if !strings.HasPrefix(key, CHANNEL_ALIAS_PREFIX) {
+			continue
+		}
+		var name string = strings.TrimPrefix(key, CHANNEL_ALIAS_PREFIX)
+		channel, nameError := client.ParseChannel(name)
+		if nameError != nil || !channel.IsNamed() {
+			fmt.Println("ERROR: Invalid channel name \"" + name + "\" in configuration file")
+			os.Exit(1)
+		}
+		number, numberError := strconv.Atoi(value)
+		if numberError != nil || number < 1 || number > NUMBER_OF_CHANNELS {
+			fmt.Println("ERROR: Alias of channel " + name + " in configuration file must be a channel number (1-" + strconv.Itoa(NUMBER_OF_CHANNELS) + ")")
+			os.Exit(1)
+		}
+		aliases[name] = int8(number)
+	}
+	return aliases
+}
+
+//Función que obtiene la frase secreta de un archivo (si se indicó) o de la variable de entorno correspondiente.
+//Retorna nil si no se configuró ninguna
+func loadPassphrase(passphraseFile string) ([]byte, error) {
+	if passphraseFile != "" {
+		content, readError := os.ReadFile(passphraseFile)
+		if readError != nil {
+			return nil, readError
+		}
+		//Se ignora el salto de línea final que suelen tener los archivos de texto
+		var passphrase string = strings.TrimRight(string(content), "\r\n")
+		if len(passphrase) == 0 {
+			return nil, errors.New("passphrase file is empty")
+		}
+		return []byte(passphrase), nil
+	}
+	if passphrase, found := os.LookupEnv(PASSPHRASE_ENVIRONMENT_VARIABLE); found && len(passphrase) > 0 {
+		return []byte(passphrase), nil
+	}
+	return nil, nil
+}
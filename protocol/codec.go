@@ -0,0 +1,103 @@
+package protocol
+
+//Archivo con el Encoder y el Decoder, que escriben y leen mensajes de una conexión
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+//Errores del Decoder
+var ErrContentTooLong = errors.New("message content is too long")
+var ErrInvalidLength = errors.New("invalid message content length")
+
+//Encoder que escribe mensajes en un io.Writer
+type Encoder struct {
+	writer io.Writer
+}
+
+func NewEncoder(writer io.Writer) *Encoder {
+	return &Encoder{writer: writer}
+}
+
+//Función que escribe un mensaje completo (con una única escritura, para que no se intercale con otros mensajes)
+func (e *Encoder) Encode(message Message) error {
+	var buffer []byte = make([]byte, HEADER_LENGTH, HEADER_LENGTH+len(message.Content))
+	putHeader(buffer, Header{Command: message.Command, Channel: message.Channel, Length: int64(len(message.Content))})
+	buffer = append(buffer, message.Content...)
+	_, writeError := e.writer.Write(buffer)
+	return writeError
+}
+
+//Función que escribe solo el header de un mensaje. El contenido (de la longitud indicada en el header) debe escribirse
+//a continuación directamente en el io.Writer
+func (e *Encoder) EncodeHeader(header Header) error {
+	if header.Length < 0 {
+		return ErrInvalidLength
+	}
+	var buffer []byte = make([]byte, HEADER_LENGTH)
+	putHeader(buffer, header)
+	_, writeError := e.writer.Write(buffer)
+	return writeError
+}
+
+//Función que serializa un header en el buffer indicado (de al menos HEADER_LENGTH bytes)
+func putHeader(buffer []byte, header Header) {
+	buffer[0] = byte(header.Command)
+	buffer[1] = byte(header.Channel)
+	binary.LittleEndian.PutUint64(buffer[2:HEADER_LENGTH], uint64(header.Length))
+}
+
+//Decoder que lee mensajes de un io.Reader. No lee más allá del mensaje, por lo que el contenido de un mensaje largo
+//puede leerse directamente del io.Reader después de DecodeHeader
+type Decoder struct {
+	reader io.Reader
+}
+
+func NewDecoder(reader io.Reader) *Decoder {
+	return &Decoder{reader: reader}
+}
+
+//Función que lee el header de un mensaje
+func (d *Decoder) DecodeHeader() (Header, error) {
+	var buffer []byte = make([]byte, HEADER_LENGTH)
+	_, readError := io.ReadFull(d.reader, buffer)
+	if readError != nil {
+		return Header{}, readError
+	}
+	var header Header = Header{
+		Command: Command(buffer[0]),
+		Channel: int8(buffer[1]),
+		Length:  int64(binary.LittleEndian.Uint64(buffer[2:])),
+	}
+	//Una longitud que no cabe en un int64 es inválida
+	if header.Length < 0 {
+		return header, ErrInvalidLength
+	}
+	return header, nil
+}
+
+//Función que lee el contenido de un mensaje cuyo header ya se leyó. Si la longitud del contenido supera maxLength, no
+//se lee y se retorna ErrContentTooLong
+func (d *Decoder) DecodeContent(header Header, maxLength int64) (Message, error) {
+	var message Message = Message{Command: header.Command, Channel: header.Channel}
+	if header.Length > maxLength {
+		return message, ErrContentTooLong
+	}
+	message.Content = make([]byte, header.Length)
+	_, readError := io.ReadFull(d.reader, message.Content)
+	if readError == io.EOF {
+		readError = io.ErrUnexpectedEOF
+	}
+	return message, readError
+}
+
+//Función que lee un mensaje completo cuyo contenido no puede superar maxLength
+func (d *Decoder) Decode(maxLength int64) (Message, error) {
+	header, headerError := d.DecodeHeader()
+	if headerError != nil {
+		return Message{Command: header.Command, Channel: header.Channel}, headerError
+	}
+	return d.DecodeContent(header, maxLength)
+}
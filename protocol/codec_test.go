@@ -0,0 +1,139 @@
+package protocol
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+//Longitud máxima del contenido usada en las pruebas
+const TEST_MAX_LENGTH = 1024
+
+func TestEncodeDecode(t *testing.T) {
+	var messages = []Message{
+		{Command: SUBSCRIBE_COMMAND, Channel: 1, Content: []byte{}},
+		{Command: OK_COMMAND, Channel: -1, Content: []byte("contenido")},
+		{Command: PUSH_DATA_COMMAND, Channel: 127, Content: bytes.Repeat([]byte{0xFF}, TEST_MAX_LENGTH)},
+	}
+	var buffer bytes.Buffer
+	var encoder *Encoder = NewEncoder(&buffer)
+	for _, message := range messages {
+		encodeError := encoder.Encode(message)
+		if encodeError != nil {
+			t.Fatalf("Encode(%v) failed: %v", message.Command, encodeError)
+		}
+	}
+	var decoder *Decoder = NewDecoder(&buffer)
+	for _, expected := range messages {
+		message, decodeError := decoder.Decode(TEST_MAX_LENGTH)
+		if decodeError != nil {
+			t.Fatalf("Decode failed: %v", decodeError)
+		}
+		if message.Command != expected.Command || message.Channel != expected.Channel || !bytes.Equal(message.Content, expected.Content) {
+			t.Errorf("Decode = %v/%v (%d bytes); expected %v/%v (%d bytes)", message.Command, message.Channel, len(message.Content), expected.Command, expected.Channel, len(expected.Content))
+		}
+	}
+	_, decodeError := decoder.Decode(TEST_MAX_LENGTH)
+	if decodeError != io.EOF {
+		t.Errorf("Decode at end of input = %v; expected io.EOF", decodeError)
+	}
+}
+
+func TestEncodeHeader(t *testing.T) {
+	var buffer bytes.Buffer
+	var encoder *Encoder = NewEncoder(&buffer)
+	encodeError := encoder.EncodeHeader(Header{Command: SEND_COMMAND, Channel: 3, Length: -1})
+	if encodeError != ErrInvalidLength || buffer.Len() != 0 {
+		t.Errorf("EncodeHeader with negative length = %v (%d bytes written); expected ErrInvalidLength", encodeError, buffer.Len())
+	}
+	var header Header = Header{Command: SEND_COMMAND, Channel: 3, Length: 5}
+	encodeError = encoder.EncodeHeader(header)
+	if encodeError != nil {
+		t.Fatalf("EncodeHeader failed: %v", encodeError)
+	}
+	buffer.WriteString("datos")
+	message, decodeError := NewDecoder(&buffer).Decode(TEST_MAX_LENGTH)
+	if decodeError != nil || message.Command != header.Command || message.Channel != header.Channel || string(message.Content) != "datos" {
+		t.Errorf("Decode after EncodeHeader = %v, %v; expected %q", message, decodeError, "datos")
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	var tests = []struct {
+		description string
+		input       []byte
+		err         error
+	}{
+		{"empty input", []byte{}, io.EOF},
+		{"truncated header", []byte{byte(OK_COMMAND), 0, 1}, io.ErrUnexpectedEOF},
+		{"negative length", []byte{byte(OK_COMMAND), 0, 0, 0, 0, 0, 0, 0, 0, 0x80}, ErrInvalidLength},
+		{"content too long", []byte{byte(OK_COMMAND), 0, 0x01, 0x04, 0, 0, 0, 0, 0, 0}, ErrContentTooLong},
+		{"truncated content", []byte{byte(OK_COMMAND), 0, 4, 0, 0, 0, 0, 0, 0, 0, 'a', 'b'}, io.ErrUnexpectedEOF},
+		{"header without content", []byte{byte(OK_COMMAND), 0, 4, 0, 0, 0, 0, 0, 0, 0}, io.ErrUnexpectedEOF},
+	}
+	for _, test := range tests {
+		_, decodeError := NewDecoder(bytes.NewReader(test.input)).Decode(TEST_MAX_LENGTH)
+		if decodeError != test.err {
+			t.Errorf("%s: Decode = %v; expected %v", test.description, decodeError, test.err)
+		}
+	}
+}
+
+//Prueba que el Decoder no falle ni reserve memoria de más con entradas arbitrarias, y que los mensajes que decodifica
+//vuelvan a codificarse como los mismos bytes
+func FuzzDecode(f *testing.F) {
+	var seed bytes.Buffer
+	NewEncoder(&seed).Encode(Message{Command: SEND_COMMAND, Channel: 2, Content: []byte("archivo")})
+	f.Add(seed.Bytes())
+	f.Add([]byte{})
+	f.Add([]byte{byte(OK_COMMAND), 0, 0, 0, 0, 0, 0, 0, 0, 0x80})
+	f.Add([]byte{byte(OK_COMMAND), 0, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0x7F})
+	f.Fuzz(func(t *testing.T, input []byte) {
+		var reader *bytes.Reader = bytes.NewReader(input)
+		var decoder *Decoder = NewDecoder(reader)
+		for {
+			var start int64 = int64(len(input)) - int64(reader.Len())
+			header, headerError := decoder.DecodeHeader()
+			if headerError != nil {
+				if headerError != io.EOF && headerError != io.ErrUnexpectedEOF && headerError != ErrInvalidLength {
+					t.Fatalf("DecodeHeader returned unexpected error: %v", headerError)
+				}
+				return
+			}
+			if header.Length < 0 {
+				t.Fatalf("DecodeHeader returned negative length %d", header.Length)
+			}
+			message, contentError := decoder.DecodeContent(header, TEST_MAX_LENGTH)
+			if errors.Is(contentError, ErrContentTooLong) {
+				if header.Length <= TEST_MAX_LENGTH {
+					t.Fatalf("DecodeContent rejected length %d", header.Length)
+				}
+				return
+			} else if contentError != nil {
+				if contentError != io.ErrUnexpectedEOF {
+					t.Fatalf("DecodeContent returned unexpected error: %v", contentError)
+				}
+				return
+			}
+			if int64(len(message.Content)) != header.Length {
+				t.Fatalf("DecodeContent returned %d bytes; header length is %d", len(message.Content), header.Length)
+			}
+			//Codificar el mensaje debe producir exactamente los bytes leídos
+			var encoded bytes.Buffer
+			encodeError := NewEncoder(&encoded).Encode(message)
+			if encodeError != nil {
+				t.Fatalf("Encode failed: %v", encodeError)
+			}
+			var end int64 = int64(len(input)) - int64(reader.Len())
+			if !bytes.Equal(encoded.Bytes(), input[start:end]) {
+				t.Fatalf("Encode(Decode(%x)) = %x", input[start:end], encoded.Bytes())
+			}
+			//Decode debe leer el mismo mensaje que DecodeHeader y DecodeContent
+			decoded, decodeError := NewDecoder(bytes.NewReader(input[start:end])).Decode(TEST_MAX_LENGTH)
+			if decodeError != nil || decoded.Command != message.Command || decoded.Channel != message.Channel || !bytes.Equal(decoded.Content, message.Content) {
+				t.Fatalf("Decode(%x) = %v, %v; expected %v", input[start:end], decoded, decodeError, message)
+			}
+		}
+	})
+}
@@ -0,0 +1,67 @@
+package protocol
+
+//Paquete con el formato de los mensajes del protocolo de intercambio de archivos: cada mensaje tiene un header de 10
+//bytes (comando, canal y longitud del contenido en little endian) seguido del contenido
+
+import "strconv"
+
+//Comando de un mensaje (primer byte del header)
+type Command int8
+
+//Comandos del protocolo
+const SUBSCRIBE_COMMAND Command = 0      //Suscripción de un listener a un canal (contenido: dirección del listener)
+const SEND_COMMAND Command = 1           //Envío de un archivo (contenido: nombre de longitud fija + archivo)
+const OK_COMMAND Command = 2             //Respuesta exitosa
+const ERROR_COMMAND Command = 3          //Respuesta de error (contenido: motivo)
+const UNSUBSCRIBE_COMMAND Command = 4    //Cancelación de una suscripción (contenido: dirección del listener o vacío en el modo push)
+const EXTENDED_SEND_COMMAND Command = 5  //Envío de un archivo en el formato extendido (flags + nombre prefijado por su longitud)
+const HELLO_COMMAND Command = 6          //Saludo con la versión y capacidades soportadas
+const RESUME_QUERY_COMMAND Command = 7   //Consulta del último byte recibido de una transferencia (contenido: identificador)
+const RESUME_OFFSET_COMMAND Command = 8  //Byte desde el cual continuar una transferencia (contenido: 8 bytes)
+const SUBSCRIBE_PUSH_COMMAND Command = 9 //Suscripción a un canal recibiendo los archivos por la misma conexión
+const PUSH_DATA_COMMAND Command = 10     //Trama de un flujo de la conexión de suscripción (identificador + datos)
+const KEEPALIVE_COMMAND Command = 11     //Trama sin contenido para mantener viva la conexión de suscripción
+const STREAM_DATA_COMMAND Command = 12   //Bloque del contenido de un envío por bloques (contenido: datos)
+const STREAM_END_COMMAND Command = 13    //Fin de un envío por bloques (contenido: hash del archivo o vacío)
+
+//Longitud del header de un mensaje
+const HEADER_LENGTH = 10
+
+//Nombres de los comandos (para los mensajes de error)
+var commandNames map[Command]string = map[Command]string{
+	SUBSCRIBE_COMMAND:      "subscribe",
+	SEND_COMMAND:           "send",
+	OK_COMMAND:             "ok",
+	ERROR_COMMAND:          "error",
+	UNSUBSCRIBE_COMMAND:    "unsubscribe",
+	EXTENDED_SEND_COMMAND:  "extended send",
+	HELLO_COMMAND:          "hello",
+	RESUME_QUERY_COMMAND:   "resume query",
+	RESUME_OFFSET_COMMAND:  "resume offset",
+	SUBSCRIBE_PUSH_COMMAND: "subscribe push",
+	PUSH_DATA_COMMAND:      "push data",
+	KEEPALIVE_COMMAND:      "keepalive",
+	STREAM_DATA_COMMAND:    "stream data",
+	STREAM_END_COMMAND:     "stream end",
+}
+
+func (c Command) String() string {
+	if name, found := commandNames[c]; found {
+		return name + " (" + strconv.Itoa(int(c)) + ")"
+	}
+	return strconv.Itoa(int(c))
+}
+
+//Header de un mensaje
+type Header struct {
+	Command Command
+	Channel int8
+	Length  int64 //Longitud del contenido
+}
+
+//Mensaje completo (header y contenido)
+type Message struct {
+	Command Command
+	Channel int8
+	Content []byte
+}
@@ -3,222 +3,343 @@ package main
 //Archivo que contiene funciones relacionadas a la interacción con el servidor en los dos modos del cliente
 
 import (
-	"encoding/binary"
+	"Client/client"
+	"context"
+	"errors"
 	"fmt"
-	"net"
 	"os"
 	"os/signal"
 	filepath2 "path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"syscall"
 )
 
-//Función para enviar una solicitud de suscripción a un determinado canal al servidor
-func subscribeToChannel(channel int8, downloadPath string) {
+//Función para suscribirse a uno o más canales y procesar los archivos recibidos hasta que se interrumpa el cliente (o
+//se cancele el contexto)
+func subscribeToChannels(ctx context.Context, fileClient *client.Client, channels []client.Channel, handler client.Handler) {
 	//Anunciar el modo en el que se ejecuta el cliente
-	fmt.Println("Receive mode: channel", channel)
-	//Se crea un listener del cliente para poder recibir mensajes del servidor cuando un archivo sea enviado
-	var listener net.Listener
-	var listenerError error
-	listener, listenerError = net.Listen("tcp", "127.0.0.1:")
-	defer listener.Close()
-	//Error check
-	if listenerError != nil {
-		fmt.Println("ERROR: Error while starting client listener for subscription: " + listenerError.Error())
-		os.Exit(2)
+	var channelNames []string
+	for _, channel := range channels {
+		channelNames = append(channelNames, channel.String())
 	}
-
-	//Se obtiene en un string la IP y puerto del listener del cliente
-	var clientAddress string = listener.Addr().String()
-
-	//El cliente se comunica con el servidor para suscribirse al canal (enviando un mensaje)
-	var message []byte
-	var command int8 = 0
-	var addressBuffer []byte = []byte(clientAddress)
-	var contentLength int64 = int64(len(addressBuffer))
-	//Se genera el mensaje como tal
-	message = createSimpleMessage(command, channel, addressBuffer)
-
-	//Verificar que la longitud del mensaje sea la correcta
-	if int64(len(message)) != 10+contentLength {
-		fmt.Printf("ERROR: Error while creating subscription message (expected length: %d, real length: %d)\n", 10+contentLength, len(message))
-		os.Exit(3)
-	}
-
-	//Ahora es posible enviar el mensaje al servidor
-	fmt.Println("Sending subscription request to server...")
-	//Se entabla la conexión con el servidor
-	var connection net.Conn
-	var connectionError error
-	connection, connectionError = net.Dial("tcp", "127.0.0.1:"+SERVER_PORT)
-	//Error check
-	if connectionError != nil {
-		fmt.Println("ERROR: Error while connecting to server: " + connectionError.Error())
-		os.Exit(2)
+	if fileClient.Push {
+		fmt.Println("Receive mode: channel", channelNames[0], "(push)")
+	} else if len(channels) == 1 {
+		fmt.Println("Receive mode: channel", channelNames[0])
+	} else {
+		fmt.Println("Receive mode: channels", strings.Join(channelNames, ", "))
 	}
-	//Se envía el mensaje
-	_, err := connection.Write(message)
-	//Error check
-	if err != nil {
-		fmt.Println("ERROR: Error while sending message to server: " + err.Error())
-		os.Exit(2)
+	//Al interrumpir el cliente (Ctrl+C o SIGTERM) se cancela la suscripción al canal y se espera a que terminen las
+	//transferencias en curso durante el periodo de gracia. Las que no terminan se interrumpen (descartando los archivos
+	//parciales) antes de salir
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	//Una segunda interrupción termina el cliente de inmediato
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+	var subscribeError error = fileClient.SubscribeChannels(ctx, channels, handler)
+	var abortedError *client.AbortedTransfersError
+	if errors.As(subscribeError, &abortedError) {
+		exitWithError(subscribeError)
+	}
+	if subscribeError != nil && !errors.Is(subscribeError, context.Canceled) {
+		exitWithError(subscribeError)
 	}
+}
 
-	fmt.Println("Request sent. Awaiting server response...")
-	//Recibir respuesta del servidor (leyendo primero el header)
-	var headerBuffer []byte = make([]byte, 10)
-	var responseCommand int8
-	var responseContentLength int64
-	_, headerError := connection.Read(headerBuffer)
-	//Error check
-	if headerError != nil {
-		fmt.Println("ERROR: Error while getting server's response header: " + headerError.Error())
-		connection.Close()
-		os.Exit(2)
+//Handler que acepta un solo archivo y, al terminar de procesarlo, cancela la suscripción
+type singleFileHandler struct {
+	handler  client.Handler
+	cancel   context.CancelFunc
+	accepted int32 //1 una vez que se aceptó un archivo
+	err      error //Resultado del archivo aceptado
+}
+
+func (h *singleFileHandler) ReceiveFile(ctx context.Context, file *client.IncomingFile) error {
+	if !atomic.CompareAndSwapInt32(&h.accepted, 0, 1) {
+		return errors.New("receiver accepts a single file")
 	}
-	//Parsear header (comando, longitud del contenido)
-	responseCommand = int8(headerBuffer[0])
-	responseContentLength = int64(binary.LittleEndian.Uint64(headerBuffer[2:]))
-	//Leer respuesta
-	var contentBuffer []byte = make([]byte, responseContentLength)
-	var content string
-	_, contentError := connection.Read(contentBuffer)
-	//Error check
-	if contentError != nil {
-		fmt.Println("ERROR: Error while getting server's response content: " + contentError.Error())
-		connection.Close()
-		os.Exit(2)
+	h.err = h.handler.ReceiveFile(ctx, file)
+	h.cancel()
+	return h.err
+}
+
+//Función para recibir un solo archivo y terminar el cliente con el resultado de su transferencia
+func receiveSingleFile(fileClient *client.Client, channels []client.Channel, handler client.Handler) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var single *singleFileHandler = &singleFileHandler{handler: handler, cancel: cancel}
+	subscribeToChannels(ctx, fileClient, channels, single)
+	if single.err != nil {
+		exitWithError(single.err)
 	}
-	//Parsear respuesta
-	content = string(contentBuffer)
+}
 
-	//Cerrar conexión, pues ya se obtuvo una respuesta
-	connection.Close()
+//Resultado del envío de un archivo
+type sendResult struct {
+	path string
+	err  error //nil si el archivo se envió correctamente
+}
 
-	//Interpretar respuesta
-	switch responseCommand {
-	case 2:
-		fmt.Println("Client successfully subscribed to channel", channel)
-		fmt.Println("Awaiting incoming file transfers on " + clientAddress + "...")
-	case 3:
-		fmt.Println("ERROR: Server error (" + content + ")")
-		os.Exit(2)
-	default:
-		fmt.Println("Invalid command received from server:", responseCommand)
-		os.Exit(2)
+//Función que expande los patrones (globs) de los archivos a enviar y verifica que todos se puedan leer antes de enviar
+//alguno. Los archivos repetidos se envían una sola vez. Los patrones inválidos o sin archivos son errores de uso
+//(código 1), y tienen prioridad sobre los archivos que no se pueden leer (código 5)
+func expandFileArguments(arguments []string) []string {
+	var paths []string
+	var exitStatus int = 0
+	var seen map[string]bool = map[string]bool{}
+	for _, argument := range arguments {
+		var matches []string = []string{argument}
+		//Los patrones se expanden aquí por si la shell no lo hizo (por ejemplo, si se indicaron entre comillas)
+		if strings.ContainsAny(argument, "*?[") {
+			var globError error
+			matches, globError = filepath2.Glob(argument)
+			if globError != nil {
+				fmt.Println("ERROR: Invalid pattern \"" + argument + "\": " + globError.Error())
+				exitStatus = client.STATUS_USAGE
+				continue
+			}
+			if len(matches) == 0 {
+				fmt.Println("ERROR: No files match pattern \"" + argument + "\"")
+				exitStatus = client.STATUS_USAGE
+				continue
+			}
+		}
+		for _, path := range matches {
+			if seen[path] {
+				continue
+			}
+			seen[path] = true
+			validationError := validateFileToSend(path)
+			if validationError != nil {
+				fmt.Println("ERROR: " + validationError.Error())
+				if exitStatus == 0 {
+					exitStatus = client.STATUS_FILESYSTEM
+				}
+				continue
+			}
+			paths = append(paths, path)
+		}
 	}
+	if exitStatus != 0 {
+		os.Exit(exitStatus)
+	}
+	return paths
+}
 
-	//Una vez exitosa la suscripción, el cliente queda esperando transferencias de archivos mediante el listener
-	//Primero dejemos corriendo una goroutine para cancelar la suscripción al canal si el programa termina
-	sig := make(chan os.Signal)
-	signal.Notify(sig, os.Interrupt, syscall.SIGTERM) //El programa responderá a las señales SIGINT y SIGTERM
-	go func() {
-		<-sig
-		unsubscribe(channel, addressBuffer)
-		os.Exit(0)
-	}()
-	//Ahora se atienden las transferencias
-	for {
-		var incomingConnection net.Conn
-		var incomingConnError error
-		//Aceptar conexión
-		incomingConnection, incomingConnError = listener.Accept()
-		//Error check
-		if incomingConnError != nil {
-			fmt.Println("ERROR: Error while accepting incoming connection: " + incomingConnError.Error())
-			os.Exit(3)
+//Función que verifica que un archivo a enviar sea un archivo regular que se pueda leer
+func validateFileToSend(path string) error {
+	file, openError := os.Open(path)
+	if openError != nil {
+		return fmt.Errorf("Error while opening file: %w", openError)
+	}
+	defer file.Close()
+	fileInfo, statError := file.Stat()
+	if statError != nil {
+		return fmt.Errorf("Error while opening file: %w", statError)
+	}
+	if fileInfo.IsDir() {
+		return errors.New("File " + path + " is a directory (use -r to send directories)")
+	}
+	if !fileInfo.Mode().IsRegular() {
+		return errors.New("File " + path + " is not a regular file")
+	}
+	if len(filepath2.Base(path)) > client.LONG_FILENAME_MAX_LENGTH {
+		return errors.New("Name of file " + path + " is too long (max length including file extension: " + strconv.Itoa(client.LONG_FILENAME_MAX_LENGTH) + " bytes)")
+	}
+	return nil
+}
+
+//Función que indica si un string está en una lista
+func containsString(list []string, value string) bool {
+	for _, listed := range list {
+		if listed == value {
+			return true
 		}
+	}
+	return false
+}
 
-		//Recibir el archivo y guardarlo
-		go receiveFile(incomingConnection, downloadPath, channel)
+//Función que verifica el nombre con el que se envía la entrada estándar
+func validateStandardInputName(name string) {
+	if name == "" || strings.ContainsAny(name, "/\\") {
+		fmt.Println("ERROR: Invalid file name \"" + name + "\" (it can't be empty or contain path separators)")
+		os.Exit(1)
+	}
+	if len(name) > client.LONG_FILENAME_MAX_LENGTH {
+		fmt.Println("ERROR: File name is too long (max length including file extension: " + strconv.Itoa(client.LONG_FILENAME_MAX_LENGTH) + " bytes)")
+		os.Exit(1)
 	}
 }
 
-//Función para enviar una solicitud de envío de archivo a un determinado canal al servidor
-func sendFileThroughChannel(channel int8, filepath string) {
+//Función para enviar el contenido de la entrada estándar con el nombre indicado. Si el servidor lo soporta, se envía
+//por bloques conforme se lee, sin esperar a que termine
+func sendStandardInput(fileClient *client.Client, channel client.Channel, name string) {
 	//Anunciar el modo en el que se ejecuta el cliente
-	fmt.Println("Send mode: file "+filepath+", channel", channel)
-	//Se obtiene el nombre del archivo y se revisa su longitud
-	var filename string = filepath2.Base(filepath)
-	if len([]byte(filename)) > FILENAME_MAX_LENGTH {
-		fmt.Println("ERROR: File name is too long (max length including file extension:", FILENAME_MAX_LENGTH, "characters)")
-		os.Exit(1)
+	fmt.Println("Send mode: standard input as "+name+", channel", channel)
+	//Al interrumpir el cliente (Ctrl+C o SIGTERM) se interrumpe el envío
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	sendError := fileClient.Send(ctx, channel, os.Stdin, name)
+	if errors.Is(sendError, context.Canceled) {
+		fmt.Println("ERROR: Transfer of file " + name + " cancelled")
+		os.Exit(2)
+	} else if sendError != nil {
+		exitWithError(sendError)
 	}
-	//Se crea la cabecera del mensaje que se enviará al servidor (comando, canal)
-	var header []byte
-	var command int8 = 1
-	//Añadir el comando al header
-	header = append(header, byte(command))
-	//Añadir el canal al header
-	header = append(header, byte(channel))
-
-	//Se abre el archivo en cuestión
-	var file *os.File
-	var fileError error
-	file, fileError = os.Open(filepath)
-	//Error check
-	if fileError != nil {
-		fmt.Println("ERROR: Error while opening file: " + fileError.Error())
+}
+
+//Función que verifica que los directorios a enviar existan antes de enviar alguno
+func expandDirectoryArguments(arguments []string) []string {
+	var valid bool = true
+	for _, argument := range arguments {
+		fileInfo, statError := os.Stat(argument)
+		if statError != nil {
+			fmt.Println("ERROR: Error while opening directory: " + statError.Error())
+			valid = false
+		} else if !fileInfo.IsDir() {
+			fmt.Println("ERROR: " + argument + " is not a directory")
+			valid = false
+		}
+	}
+	if !valid {
 		os.Exit(5)
 	}
+	return arguments
+}
 
-	//Se realiza el envío del archivo al servidor
-	sendFile(header, []byte(filename), file)
+//Función para enviar uno o más directorios completos a todos los clientes suscritos a un determinado canal, uno tras otro
+func sendDirectoriesThroughChannel(fileClient *client.Client, channel client.Channel, directories []string) {
+	//Anunciar el modo en el que se ejecuta el cliente
+	if len(directories) == 1 {
+		fmt.Println("Send mode: directory "+directories[0]+", channel", channel)
+	} else {
+		fmt.Println("Send mode: directories "+strings.Join(directories, ", ")+", channel", channel)
+	}
+	//Al interrumpir el cliente (Ctrl+C o SIGTERM) se interrumpe el envío en curso y no se envía el resto
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	for _, directory := range directories {
+		sendError := fileClient.SendDirectory(ctx, channel, directory)
+		if errors.Is(sendError, context.Canceled) {
+			fmt.Println("ERROR: Transfer of directory " + directory + " cancelled")
+			os.Exit(2)
+		} else if sendError != nil {
+			exitWithError(sendError)
+		}
+		fmt.Println("Directory " + directory + " sent")
+	}
 }
 
-func unsubscribe(channel int8, address []byte) {
-	//Anunciar que el cliente va a cancelar su suscripción al canal
-	fmt.Printf("\nCancelling subscription of %v to channel %d...\n", string(address), channel)
-	//Armar el mensaje a enviar al servidor
-	var message []byte = createSimpleMessage(4, channel, address)
-	//Conectarse al servidor para enviar el mensaje
-	connection, connError := net.Dial("tcp", "127.0.0.1:"+SERVER_PORT)
-	if connError != nil {
-		fmt.Println("ERROR: Error while connecting to server: " + connError.Error())
-		os.Exit(2)
+//Función para enviar uno o más archivos a todos los clientes suscritos a un determinado canal, hasta parallel a la vez.
+//Al terminar se muestra un resumen, y si falló algún envío el cliente termina con el código de salida correspondiente
+func sendFilesThroughChannel(fileClient *client.Client, channel client.Channel, paths []string, parallel int) {
+	//Anunciar el modo en el que se ejecuta el cliente
+	if len(paths) == 1 {
+		fmt.Println("Send mode: file "+paths[0]+", channel", channel)
+	} else {
+		fmt.Println("Send mode: "+strconv.Itoa(len(paths))+" files, channel", channel)
 	}
-	//Asegurarse de cerrar la conexión al salir
-	defer connection.Close()
-	//Enviar mensaje
-	_, sendError := connection.Write(message)
-	if sendError != nil {
-		fmt.Println("ERROR: Error while sending message to server: " + sendError.Error())
-		os.Exit(2)
+	//Al enviar varios archivos a la vez, los mensajes de progreso de cada envío se mezclarían, por lo que solo se
+	//muestra el resultado de cada uno
+	if parallel > 1 && len(paths) > 1 {
+		fileClient.Log = nil
 	}
-	fmt.Println("Request sent. Awaiting server response...")
-	//Recibir respuesta del servidor (leyendo primero el header)
-	var headerBuffer []byte = make([]byte, 10)
-	var responseCommand int8
-	var responseContentLength int64
-	_, headerError := connection.Read(headerBuffer)
-	//Error check
-	if headerError != nil {
-		fmt.Println("ERROR: Error while getting server's response header: " + headerError.Error())
-		os.Exit(2)
+	//Al interrumpir el cliente (Ctrl+C o SIGTERM) se interrumpen los envíos en curso y no se inician los restantes
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	var results []sendResult = make([]sendResult, len(paths))
+	var slots chan struct{} = make(chan struct{}, parallel)
+	var transfers sync.WaitGroup
+	for i, path := range paths {
+		results[i].path = path
+		//Esperar a que haya un envío disponible
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			results[i].err = ctx.Err()
+			continue
+		}
+		transfers.Add(1)
+		go func(result *sendResult) {
+			defer transfers.Done()
+			defer func() { <-slots }()
+			result.err = sendFile(ctx, fileClient, channel, result.path)
+			if result.err == nil && fileClient.Log == nil {
+				fmt.Println("File " + result.path + " sent")
+			} else if result.err != nil && len(paths) > 1 {
+				fmt.Println("ERROR: Transfer of file " + result.path + " failed: " + result.err.Error())
+			}
+		}(&results[i])
 	}
-	//Parsear header (comando, longitud del contenido)
-	responseCommand = int8(headerBuffer[0])
-	responseContentLength = int64(binary.LittleEndian.Uint64(headerBuffer[2:]))
-	//Leer respuesta
-	var contentBuffer []byte = make([]byte, responseContentLength)
-	var content string
-	_, contentError := connection.Read(contentBuffer)
-	//Error check
-	if contentError != nil {
-		fmt.Println("ERROR: Error while getting server's response content: " + contentError.Error())
-		os.Exit(2)
+	transfers.Wait()
+	//Con un solo archivo basta con el error (si lo hubo)
+	if len(paths) == 1 {
+		if errors.Is(results[0].err, context.Canceled) {
+			fmt.Println("ERROR: Transfer of file " + paths[0] + " cancelled")
+			os.Exit(2)
+		} else if results[0].err != nil {
+			exitWithError(results[0].err)
+		}
+		return
 	}
-	//Parsear respuesta
-	content = string(contentBuffer)
+	printSendSummary(results)
+}
 
-	//Interpretar respuesta
-	switch responseCommand {
-	case 2:
-		fmt.Println("Client successfully unsubscribed from channel", channel)
-	case 3:
-		fmt.Println("ERROR: Server error (" + content + ")")
-		os.Exit(2)
-	default:
-		fmt.Println("Invalid command received from server:", responseCommand)
+//Función que envía un archivo con su nombre (sin el directorio)
+func sendFile(ctx context.Context, fileClient *client.Client, channel client.Channel, path string) error {
+	file, openError := os.Open(path)
+	if openError != nil {
+		return &client.Error{Status: client.STATUS_FILESYSTEM, Message: "Error while opening file", Err: openError}
+	}
+	defer file.Close()
+	return fileClient.Send(ctx, channel, file, filepath2.Base(path))
+}
+
+//Función que muestra el resultado del envío de cada archivo y, si falló alguno, termina el cliente con el código de
+//salida del primero que falló
+func printSendSummary(results []sendResult) {
+	fmt.Println("\nSummary:")
+	var sent int = 0
+	var firstError error
+	for _, result := range results {
+		if result.err == nil {
+			fmt.Println("  sent\t\t" + result.path)
+			sent++
+			continue
+		}
+		if firstError == nil {
+			firstError = result.err
+		}
+		if errors.Is(result.err, context.Canceled) {
+			fmt.Println("  cancelled\t" + result.path)
+		} else {
+			fmt.Println("  failed\t" + result.path + " (" + result.err.Error() + ")")
+		}
+	}
+	fmt.Println(strconv.Itoa(sent) + " of " + strconv.Itoa(len(results)) + " files sent")
+	if firstError != nil {
+		var clientError *client.Error
+		if errors.As(firstError, &clientError) {
+			os.Exit(clientError.Status)
+		}
 		os.Exit(2)
 	}
 }
+
+//Función que muestra un error de la biblioteca y termina el cliente con el código de salida correspondiente
+func exitWithError(err error) {
+	fmt.Println("ERROR: " + err.Error())
+	var clientError *client.Error
+	if errors.As(err, &clientError) {
+		os.Exit(clientError.Status)
+	}
+	os.Exit(2)
+}
@@ -0,0 +1,111 @@
+package main
+
+//Archivo con la configuración de TLS para las conexiones con el servidor y el listener de recepción
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+)
+
+//Configuración de TLS para las conexiones iniciadas por el cliente (nil si no se utiliza TLS)
+var clientTLSConfig *tls.Config
+
+//Configuración de TLS para el listener que recibe las conexiones del servidor (nil si no se utiliza TLS)
+var listenerTLSConfig *tls.Config
+
+//Opciones de TLS indicadas por línea de comandos
+type tlsFlags struct {
+	enabled    *bool   //Utilizar TLS en todas las conexiones
+	caFile     *string //Bundle de certificados de CA con el que se verifica al servidor
+	certFile   *string //Certificado del cliente
+	keyFile    *string //Llave privada del certificado del cliente
+	serverName *string //Nombre esperado en el certificado del servidor
+}
+
+//Función que añade las opciones de TLS a los flags de un modo del cliente
+func addTLSFlags(flags *flag.FlagSet) tlsFlags {
+	return tlsFlags{
+		enabled:    flags.Bool("tls", false, "use TLS for all connections"),
+		caFile:     flags.String("ca", "", "PEM bundle of CA certificates used to verify the server"),
+		certFile:   flags.String("cert", "", "PEM client certificate"),
+		keyFile:    flags.String("key", "", "PEM private key of the client certificate"),
+		serverName: flags.String("server-name", "", "name expected in the server's certificate"),
+	}
+}
+
+//Función que carga la configuración de TLS a partir de las opciones indicadas. En el modo de recepción, el certificado
+//del cliente también se presenta en el listener al que se conecta el servidor, por lo que es obligatorio, al igual que
+//la CA con la que se verifica al servidor que se conecta (sin ella, cualquiera podría entregar archivos al listener)
+func loadTLSConfig(options tlsFlags, receiveMode bool) {
+	if !*options.enabled {
+		if *options.caFile != "" || *options.certFile != "" || *options.keyFile != "" || *options.serverName != "" {
+			fmt.Println("ERROR: TLS options require the \"-tls\" flag")
+			os.Exit(1)
+		}
+		return
+	}
+	if (*options.certFile == "") != (*options.keyFile == "") {
+		fmt.Println("ERROR: Flags \"-cert\" and \"-key\" must be used together")
+		os.Exit(1)
+	}
+	if receiveMode && *options.certFile == "" {
+		fmt.Println("ERROR: TLS receive mode requires \"-cert\" and \"-key\" for the incoming connections listener")
+		os.Exit(1)
+	}
+	if receiveMode && *options.caFile == "" {
+		fmt.Println("ERROR: TLS receive mode requires \"-ca\" to verify the server's connections to the incoming connections listener")
+		os.Exit(1)
+	}
+	//Configuración para las conexiones iniciadas por el cliente
+	clientTLSConfig = &tls.Config{MinVersion: tls.VersionTLS12, ServerName: *options.serverName}
+	var certificates []tls.Certificate
+	if *options.certFile != "" {
+		certificate, certificateError := tls.LoadX509KeyPair(*options.certFile, *options.keyFile)
+		if certificateError != nil {
+			fmt.Println("ERROR: Error while loading client certificate: " + certificateError.Error())
+			os.Exit(1)
+		}
+		certificates = []tls.Certificate{certificate}
+		clientTLSConfig.Certificates = certificates
+	}
+	var pool *x509.CertPool
+	if *options.caFile != "" {
+		var poolError error
+		pool, poolError = loadCertificatePool(*options.caFile)
+		if poolError != nil {
+			fmt.Println("ERROR: Error while loading CA bundle: " + poolError.Error())
+			os.Exit(1)
+		}
+		clientTLSConfig.RootCAs = pool
+	}
+	if receiveMode {
+		listenerTLSConfig = newListenerTLSConfig(certificates, pool)
+	}
+}
+
+//Función que crea la configuración de TLS del listener: el servidor debe presentar un certificado firmado por la CA
+func newListenerTLSConfig(certificates []tls.Certificate, pool *x509.CertPool) *tls.Config {
+	return &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: certificates,
+		ClientCAs:    pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	}
+}
+
+//Función que carga un bundle de certificados de CA en formato PEM
+func loadCertificatePool(caFile string) (*x509.CertPool, error) {
+	content, readError := os.ReadFile(caFile)
+	if readError != nil {
+		return nil, readError
+	}
+	var pool *x509.CertPool = x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(content) {
+		return nil, errors.New("no certificates found in " + caFile)
+	}
+	return pool, nil
+}
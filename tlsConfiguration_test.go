@@ -0,0 +1,111 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+)
+
+//CA creada en memoria para las pruebas
+type testCA struct {
+	certificate *x509.Certificate
+	key         *ecdsa.PrivateKey
+}
+
+//Función que crea una CA en memoria
+func newTestCA(t *testing.T, name string) *testCA {
+	key, keyError := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if keyError != nil {
+		t.Fatalf("could not generate CA key: %v", keyError)
+	}
+	var template *x509.Certificate = &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, certificateError := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if certificateError != nil {
+		t.Fatalf("could not create CA certificate: %v", certificateError)
+	}
+	certificate, parseError := x509.ParseCertificate(der)
+	if parseError != nil {
+		t.Fatalf("could not parse CA certificate: %v", parseError)
+	}
+	return &testCA{certificate: certificate, key: key}
+}
+
+//Función que emite un certificado firmado por la CA, válido para 127.0.0.1 como servidor y como cliente
+func (ca *testCA) issue(t *testing.T, name string) tls.Certificate {
+	key, keyError := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if keyError != nil {
+		t.Fatalf("could not generate key: %v", keyError)
+	}
+	var template *x509.Certificate = &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, certificateError := x509.CreateCertificate(rand.Reader, template, ca.certificate, &key.PublicKey, ca.key)
+	if certificateError != nil {
+		t.Fatalf("could not create certificate: %v", certificateError)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+//Función que crea un pool con el certificado de la CA
+func (ca *testCA) pool() *x509.CertPool {
+	var pool *x509.CertPool = x509.NewCertPool()
+	pool.AddCert(ca.certificate)
+	return pool
+}
+
+//Prueba que el listener de recepción solo acepte conexiones de quien presente un certificado firmado por la CA
+func TestListenerTLSConfig(t *testing.T) {
+	var ca *testCA = newTestCA(t, "test-ca")
+	var otherCA *testCA = newTestCA(t, "other-ca")
+	var listenerConfig *tls.Config = newListenerTLSConfig([]tls.Certificate{ca.issue(t, "client")}, ca.pool())
+	var tests = []struct {
+		description  string
+		certificates []tls.Certificate //Certificado que presenta el servidor al conectarse al listener
+		accepted     bool
+	}{
+		{"server signed by the CA", []tls.Certificate{ca.issue(t, "server")}, true},
+		{"server without certificate", nil, false},
+		{"server signed by another CA", []tls.Certificate{otherCA.issue(t, "server")}, false},
+	}
+	for _, test := range tests {
+		serverSide, clientSide := net.Pipe()
+		var dialerConfig *tls.Config = &tls.Config{RootCAs: ca.pool(), ServerName: "127.0.0.1", Certificates: test.certificates}
+		var dialResult chan error = make(chan error, 1)
+		go func() {
+			var connection *tls.Conn = tls.Client(serverSide, dialerConfig)
+			dialResult <- connection.Handshake()
+			//Con TLS 1.3 el rechazo del certificado llega después del saludo, al leer
+			connection.Read(make([]byte, 1))
+			connection.Close()
+		}()
+		var listenerConnection *tls.Conn = tls.Server(clientSide, listenerConfig)
+		listenerConnection.SetDeadline(time.Now().Add(5 * time.Second))
+		var handshakeError error = listenerConnection.Handshake()
+		listenerConnection.Close()
+		<-dialResult
+		if (handshakeError == nil) != test.accepted {
+			t.Errorf("%s: handshake error = %v; expected accepted = %v", test.description, handshakeError, test.accepted)
+		}
+	}
+}